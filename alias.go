@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 输入的第一个词是别名时替换为其展开内容，其余参数原样追加。
+// 展开结果只替换一次，不会递归展开
+func expandAlias(state *ChatState, input string) string {
+	name, rest, _ := strings.Cut(input, " ")
+	expansion, ok := state.Aliases[name]
+	if !ok {
+		return input
+	}
+	if rest = strings.TrimSpace(rest); rest != "" {
+		expansion += " " + rest
+	}
+	if state.Debug {
+		fmt.Printf(msg("[DEBUG] 别名 %s 展开为: %q\n"), name, expansion)
+	}
+	return expansion
+}
+
+func handleAliasCommand(input string, state *ChatState) {
+	args := strings.TrimSpace(strings.TrimPrefix(input, "/alias"))
+	if args == "" || args == "list" {
+		listAliases(state)
+		return
+	}
+
+	name, expansion, _ := strings.Cut(args, " ")
+	expansion = strings.TrimSpace(expansion)
+	if expansion == "" {
+		fmt.Println(msg("用法: /alias <名称> <展开内容> | /alias list"))
+		return
+	}
+	if strings.HasPrefix(name, "/") || name == "exit" {
+		fmt.Println(msg("错误：别名不能以 / 开头或与内置命令同名"))
+		return
+	}
+
+	if state.Aliases == nil {
+		state.Aliases = map[string]string{}
+	}
+	state.Aliases[name] = expansion
+	fmt.Printf(msg("已设置别名: %s => %s\n"), name, expansion)
+}
+
+func listAliases(state *ChatState) {
+	if len(state.Aliases) == 0 {
+		fmt.Println(msg("暂无别名(可在配置文件的 aliases 中定义)"))
+		return
+	}
+
+	names := make([]string, 0, len(state.Aliases))
+	for name := range state.Aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Println(msg("别名:"))
+	for _, name := range names {
+		fmt.Printf("  %s => %s\n", name, state.Aliases[name])
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// -format anthropic 的响应解析。请求体见 encoder.go 中的 anthropicEncoder；
+// 响应的 SSE 事件和非流式响应体都与 OpenAI 格式不同，这里转换为统一的事件和响应结构
+
+// Messages API 要求的版本请求头
+const anthropicVersion = "2023-06-01"
+
+// Anthropic 用 x-api-key 传递密钥；显式指定 -auth-header/-auth-prefix 时(如经过代理)沿用该设置
+func setAnthropicHeaders(req *http.Request) {
+	req.Header.Set("anthropic-version", anthropicVersion)
+	if isFlagSet("auth-header") || isFlagSet("auth-prefix") {
+		req.Header.Set(*authHeader, *authPrefix+*apiKey)
+		return
+	}
+	req.Header.Set("x-api-key", *apiKey)
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+func (u anthropicUsage) toUsage() *Usage {
+	return &Usage{
+		PromptTokens:     u.InputTokens,
+		CompletionTokens: u.OutputTokens,
+		TotalTokens:      u.InputTokens + u.OutputTokens,
+	}
+}
+
+type anthropicError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// 流式响应中的一个事件，按 type 区分：message_start、content_block_delta、
+// message_delta、message_stop、error 等，其余事件(ping、content_block_start 等)忽略
+type anthropicStreamEvent struct {
+	Type    string `json:"type"`
+	Message *struct {
+		ID    string         `json:"id"`
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message,omitempty"`
+	Delta struct {
+		Text       string `json:"text,omitempty"`
+		Thinking   string `json:"thinking,omitempty"`
+		StopReason string `json:"stop_reason,omitempty"`
+	} `json:"delta"`
+	Usage *anthropicUsage `json:"usage,omitempty"`
+	Error *anthropicError `json:"error,omitempty"`
+}
+
+// 一次流式响应的解析状态：输入 token 数在 message_start 中，输出 token 数在 message_delta 中
+type anthropicStream struct {
+	requestID string
+	usage     anthropicUsage
+	hasUsage  bool
+}
+
+// 解析一行 data，返回转换后的事件；done 表示收到 message_stop
+func (s *anthropicStream) decode(data []byte) (events []StreamEvent, done bool, err error) {
+	var event anthropicStreamEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, false, fmt.Errorf(msg("解析JSON失败: %w"), err)
+	}
+
+	switch event.Type {
+	case "message_start":
+		if event.Message != nil {
+			s.requestID = event.Message.ID
+			s.usage.InputTokens = event.Message.Usage.InputTokens
+			s.hasUsage = true
+		}
+	case "content_block_delta":
+		if event.Delta.Text != "" || event.Delta.Thinking != "" {
+			events = append(events, StreamEvent{Content: event.Delta.Text, ReasoningContent: event.Delta.Thinking})
+		}
+	case "message_delta":
+		if event.Usage != nil {
+			s.usage.OutputTokens = event.Usage.OutputTokens
+			s.hasUsage = true
+		}
+		if event.Delta.StopReason != "" {
+			events = append(events, StreamEvent{FinishReason: event.Delta.StopReason})
+		}
+	case "message_stop":
+		return nil, true, nil
+	case "error":
+		if event.Error != nil {
+			return nil, false, fmt.Errorf(msg("服务端返回错误(%s): %s"), event.Error.Type, event.Error.Message)
+		}
+	}
+	return events, false, nil
+}
+
+func (s *anthropicStream) currentUsage() *Usage {
+	if !s.hasUsage {
+		return nil
+	}
+	return s.usage.toUsage()
+}
+
+// 非流式响应，回答按内容块返回
+type anthropicResponse struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Content []struct {
+		Type     string `json:"type"`
+		Text     string `json:"text,omitempty"`
+		Thinking string `json:"thinking,omitempty"`
+	} `json:"content"`
+	StopReason string          `json:"stop_reason"`
+	Usage      *anthropicUsage `json:"usage,omitempty"`
+}
+
+// 转换为 OpenAI 格式的 ChatResponse，只有一个回答
+func decodeAnthropicResponse(body io.Reader) (ChatResponse, error) {
+	var resp anthropicResponse
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return ChatResponse{}, err
+	}
+
+	choice := chatChoice{FinishReason: resp.StopReason}
+	for _, block := range resp.Content {
+		switch block.Type {
+		case "text":
+			choice.Message.Content += block.Text
+		case "thinking":
+			choice.Message.ReasoningContent += block.Thinking
+		}
+	}
+	chat := ChatResponse{ID: resp.ID, Model: resp.Model, Choices: []chatChoice{choice}}
+	if resp.Usage != nil {
+		chat.Usage = resp.Usage.toUsage()
+	}
+	return chat, nil
+}
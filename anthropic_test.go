@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testAnthropicSSE = "event: message_start\n" +
+	"data: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\",\"usage\":{\"input_tokens\":10,\"output_tokens\":1}}}\n\n" +
+	"event: ping\ndata: {\"type\":\"ping\"}\n\n" +
+	"data: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"thinking_delta\",\"thinking\":\"想一想\"}}\n\n" +
+	"data: {\"type\":\"content_block_delta\",\"index\":1,\"delta\":{\"type\":\"text_delta\",\"text\":\"你好\"}}\n\n" +
+	"data: {\"type\":\"content_block_delta\",\"index\":1,\"delta\":{\"type\":\"text_delta\",\"text\":\"，世界\"}}\n\n" +
+	"data: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"output_tokens\":5}}\n\n" +
+	"data: {\"type\":\"message_stop\"}\n\n"
+
+func TestAnthropicStream(t *testing.T) {
+	defer func(old string) { *requestFormat = old }(*requestFormat)
+	*requestFormat = "anthropic"
+
+	result, err := processStreamResponse(strings.NewReader(testAnthropicSSE), &ChatState{}, false, func() {})
+	if err != nil {
+		t.Fatalf("processStreamResponse: %v", err)
+	}
+	if result.Content != "你好，世界" || result.Reasoning != "想一想" {
+		t.Errorf("Content = %q, Reasoning = %q", result.Content, result.Reasoning)
+	}
+	if !result.Done || result.RequestID != "msg_1" {
+		t.Errorf("Done = %v, RequestID = %q", result.Done, result.RequestID)
+	}
+	if result.Usage == nil || *result.Usage != (Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}) {
+		t.Errorf("Usage = %+v", result.Usage)
+	}
+}
+
+func TestAnthropicStreamError(t *testing.T) {
+	defer func(old string) { *requestFormat = old }(*requestFormat)
+	*requestFormat = "anthropic"
+
+	body := "data: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n"
+	_, err := processStreamResponse(strings.NewReader(body), &ChatState{}, false, func() {})
+	if err == nil || !strings.Contains(err.Error(), "Overloaded") {
+		t.Errorf("err = %v, want the overloaded error", err)
+	}
+}
+
+func TestDecodeAnthropicResponse(t *testing.T) {
+	body := `{"id":"msg_2","model":"claude","content":[{"type":"thinking","thinking":"嗯"},{"type":"text","text":"回答"}],` +
+		`"stop_reason":"end_turn","usage":{"input_tokens":3,"output_tokens":2}}`
+	chat, err := decodeAnthropicResponse(strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("decodeAnthropicResponse: %v", err)
+	}
+	if len(chat.Choices) != 1 || chat.Choices[0].Message.Content != "回答" || chat.Choices[0].Message.ReasoningContent != "嗯" {
+		t.Errorf("Choices = %+v", chat.Choices)
+	}
+	if chat.ID != "msg_2" || chat.Usage == nil || chat.Usage.TotalTokens != 5 {
+		t.Errorf("ID = %q, Usage = %+v", chat.ID, chat.Usage)
+	}
+}
+
+func TestAnthropicRequestHeaders(t *testing.T) {
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, testAnthropicSSE)
+	}))
+	defer server.Close()
+
+	defer func(format, endpoint, key string) {
+		*requestFormat, *apiEndpoint, *apiKey = format, endpoint, key
+	}(*requestFormat, *apiEndpoint, *apiKey)
+	*requestFormat, *apiEndpoint, *apiKey = "anthropic", server.URL, "sk-test"
+
+	state := &ChatState{Model: "claude", Client: server.Client()}
+	resp, _, err := postChatRequest(context.Background(), state, StreamRequest{
+		Model:    "claude",
+		Messages: []Message{{Role: "user", Content: "你好"}},
+	})
+	if err != nil {
+		t.Fatalf("postChatRequest: %v", err)
+	}
+	resp.Body.Close()
+
+	if got := header.Get("anthropic-version"); got != anthropicVersion {
+		t.Errorf("anthropic-version = %q, want %q", got, anthropicVersion)
+	}
+	if got := header.Get("x-api-key"); got != "sk-test" {
+		t.Errorf("x-api-key = %q, want %q", got, "sk-test")
+	}
+	if got := header.Get("Authorization"); got != "" {
+		t.Errorf("Authorization = %q, want empty", got)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// 服务端返回的错误，Body 保留原始响应体供调试
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	switch {
+	case e.Code != "" && e.Message != "":
+		return fmt.Sprintf(msg("API错误 %d: %s: %s"), e.StatusCode, e.Code, e.Message)
+	case e.Message != "":
+		return fmt.Sprintf(msg("API错误 %d: %s"), e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf(msg("API错误 %d: %s"), e.StatusCode, e.Body)
+}
+
+// 兼容的错误格式：
+//
+//	OpenAI:    {"error": {"message": "...", "type": "...", "code": "..."}}
+//	Anthropic: {"type": "error", "error": {"type": "...", "message": "..."}}
+//	DashScope: {"code": "...", "message": "...", "request_id": "..."}
+type errorResponse struct {
+	Error *struct {
+		Message string          `json:"message"`
+		Type    string          `json:"type"`
+		Code    json.RawMessage `json:"code"`
+	} `json:"error"`
+	Code    json.RawMessage `json:"code"`
+	Message string          `json:"message"`
+}
+
+func parseAPIError(statusCode int, body []byte) *APIError {
+	apiErr := &APIError{StatusCode: statusCode, Body: strings.TrimSpace(string(body))}
+
+	var resp errorResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return apiErr
+	}
+	if resp.Error != nil {
+		apiErr.Message = resp.Error.Message
+		apiErr.Code = rawCode(resp.Error.Code)
+		if apiErr.Code == "" {
+			apiErr.Code = resp.Error.Type
+		}
+		return apiErr
+	}
+	apiErr.Code = rawCode(resp.Code)
+	apiErr.Message = resp.Message
+	return apiErr
+}
+
+// code 字段可能是字符串、数字或 null
+func rawCode(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	if code := string(raw); code != "null" {
+		return code
+	}
+	return ""
+}
+
+// 模型名称错误或模型已下线时各服务商的错误码和提示不尽相同，按关键字识别
+func isModelNotFound(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || (apiErr.StatusCode != http.StatusBadRequest && apiErr.StatusCode != http.StatusNotFound) {
+		return false
+	}
+	code := strings.ToLower(apiErr.Code)
+	if strings.Contains(code, "model_not_found") || strings.Contains(code, "modelnotfound") {
+		return true
+	}
+	text := strings.ToLower(apiErr.Message + " " + apiErr.Body)
+	if !strings.Contains(text, "model") {
+		return false
+	}
+	for _, hint := range []string{"not found", "does not exist", "not exist", "deprecated", "decommissioned"} {
+		if strings.Contains(text, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// 模型不存在时附带可替换的模型，原错误仍可通过 errors.As 取得
+type modelNotFoundError struct {
+	model string
+	err   error
+}
+
+func (e *modelNotFoundError) Error() string {
+	text := fmt.Sprintf(msg("%v\n模型 %s 不存在或已下线"), e.err, e.model)
+	if alternatives := suggestModels(e.model); len(alternatives) > 0 {
+		text += msg("，可改用: ") + strings.Join(alternatives, ", ")
+	}
+	return text + msg("(交互模式下用 /model <模型名> 切换)")
+}
+
+func (e *modelNotFoundError) Unwrap() error {
+	return e.err
+}
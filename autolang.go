@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// 按文字系统粗略判断语言，拉丁字母无法区分具体语言，不做判断
+var scriptLanguages = []struct {
+	table    *unicode.RangeTable
+	language string
+}{
+	{unicode.Hangul, "Korean"},
+	{unicode.Han, "Chinese"},
+	{unicode.Cyrillic, "Russian"},
+	{unicode.Arabic, "Arabic"},
+	{unicode.Thai, "Thai"},
+	{unicode.Greek, "Greek"},
+	{unicode.Hebrew, "Hebrew"},
+	{unicode.Devanagari, "Hindi"},
+}
+
+// 用户消息中占多数的语言，出现假名时汉字按日语计算
+func detectLanguage(text string) string {
+	counts := map[string]int{}
+	kana := false
+	for _, r := range text {
+		if unicode.In(r, unicode.Hiragana, unicode.Katakana) {
+			kana = true
+			counts["Japanese"]++
+			continue
+		}
+		for _, sl := range scriptLanguages {
+			if unicode.Is(sl.table, r) {
+				counts[sl.language]++
+				break
+			}
+		}
+		if unicode.Is(unicode.Latin, r) {
+			counts[""]++
+		}
+	}
+	if kana {
+		counts["Japanese"] += counts["Chinese"]
+		delete(counts, "Chinese")
+	}
+
+	best := ""
+	for _, language := range sortedKeys(counts) {
+		if counts[language] > counts[best] {
+			best = language
+		}
+	}
+	return best
+}
+
+// -auto-lang: 在发送的最后一条用户消息后附加回复语言的要求，只影响本次请求，不写入对话历史
+func withLanguageHint(messages []Message) []Message {
+	last := len(messages) - 1
+	if last < 0 || messages[last].Role != "user" {
+		return messages
+	}
+	language := detectLanguage(messages[last].Content)
+	if language == "" {
+		return messages
+	}
+
+	hinted := append([]Message(nil), messages...)
+	hinted[last].Content += fmt.Sprintf("\n\n(Respond in %s.)", language)
+	return hinted
+}
+
+// /autolang [on|off]: 查看或切换按用户消息语言要求回复语言
+func handleAutoLang(input string, state *ChatState) {
+	switch strings.TrimSpace(strings.TrimPrefix(input, "/autolang")) {
+	case "":
+	case "on":
+		state.AutoLang = true
+	case "off":
+		state.AutoLang = false
+	default:
+		fmt.Println(msg("用法: /autolang [on|off]"))
+		return
+	}
+	if state.AutoLang {
+		fmt.Println(msg("自动回复语言: 开启"))
+	} else {
+		fmt.Println(msg("自动回复语言: 关闭"))
+	}
+}
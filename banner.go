@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// 默认欢迎信息，自定义模板(-banner-file)可使用相同的变量:
+// {{model}} 当前模型, {{debug}} 调试模式, {{history}} 历史记录文件
+const defaultBanner = `
+阿里云百炼对话客户端
+----------------------------------
+当前配置:
+  模型: {{model}}
+  调试模式: {{debug}}
+  历史记录文件: {{history}}
+----------------------------------
+命令说明:
+  /help        显示帮助
+  /info        查看会话信息
+  /reset       重置对话
+  /model <模型名> 切换模型(Ctrl+N 循环切换)
+  /debug       切换调试模式
+  /think       切换深度思考
+  /history     查看命令历史
+  /prompt      管理提示词模板
+  exit         退出程序
+----------------------------------
+`
+
+var bannerModes = map[string]bool{"off": true, "default": true, "custom": true}
+
+func validBannerMode() error {
+	if !bannerModes[*bannerMode] {
+		return fmt.Errorf(msg("不支持的 -banner 取值 %q，可选 off|default|custom"), *bannerMode)
+	}
+	if *bannerMode == "custom" && *bannerFile == "" {
+		return errors.New(msg("-banner custom 需要同时指定 -banner-file"))
+	}
+	return nil
+}
+
+// 读取自定义模板失败时退回默认欢迎信息
+func bannerTemplate() string {
+	if *bannerMode != "custom" {
+		return msg(defaultBanner)
+	}
+	data, err := os.ReadFile(*bannerFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, msg("读取欢迎信息模板失败，使用默认模板: %v\n"), err)
+		return msg(defaultBanner)
+	}
+	return string(data)
+}
+
+// 替换模板中的 {{变量}}，未知变量保持原样
+func renderBanner(tmpl string, state *ChatState) string {
+	values := map[string]string{
+		"model":   state.Model,
+		"debug":   strconv.FormatBool(state.Debug),
+		"history": getHistoryFilePath(),
+	}
+	return varPlaceholderExpr.ReplaceAllStringFunc(tmpl, func(match string) string {
+		if value, ok := values[varPlaceholderExpr.FindStringSubmatch(match)[1]]; ok {
+			return value
+		}
+		return match
+	})
+}
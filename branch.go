@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+)
+
+const defaultBranch = "main"
+
+// 对话分支，当前分支的内容在 state.History 中，其余分支保存在 state.Branches
+type Branch struct {
+	History []Message    `json:"history"`
+	Pinned  map[int]bool `json:"-"`
+}
+
+func currentBranch(state *ChatState) string {
+	if state.Branch == "" {
+		return defaultBranch
+	}
+	return state.Branch
+}
+
+func branchExists(state *ChatState, name string) bool {
+	_, ok := state.Branches[name]
+	return ok || name == currentBranch(state)
+}
+
+// 保存当前分支的副本，切换分支前调用
+func stashBranch(state *ChatState) {
+	if state.Branches == nil {
+		state.Branches = map[string]Branch{}
+	}
+	state.Branches[currentBranch(state)] = Branch{
+		History: append([]Message(nil), state.History...),
+		Pinned:  maps.Clone(state.Pinned),
+	}
+}
+
+// /fork [名称]: 复制当前对话为新分支并切换过去，两个分支共享此前的对话
+func handleFork(input string, state *ChatState) {
+	parts := strings.Fields(input)
+	if len(parts) > 2 {
+		fmt.Println(msg("用法: /fork [分支名]"))
+		return
+	}
+
+	var name string
+	if len(parts) == 2 {
+		name = parts[1]
+	} else {
+		for i := 1; name == ""; i++ {
+			if candidate := fmt.Sprintf("fork-%d", i); !branchExists(state, candidate) {
+				name = candidate
+			}
+		}
+	}
+	if branchExists(state, name) {
+		fmt.Printf(msg("错误：分支 %s 已存在\n"), name)
+		return
+	}
+
+	parent := currentBranch(state)
+	stashBranch(state)
+	state.Branch = name
+	fmt.Printf(msg("已从分支 %s 创建并切换到分支 %s(%d 轮对话)\n"), parent, name, countUserTurns(state.History))
+}
+
+func handleBranchCommand(input string, state *ChatState) {
+	parts := strings.Fields(input)
+	switch {
+	case len(parts) == 1 || (len(parts) == 2 && parts[1] == "list"):
+		listBranches(state)
+	case len(parts) == 3 && parts[1] == "switch":
+		switchBranch(state, parts[2])
+	default:
+		fmt.Println(msg("用法: /branch list | /branch switch <分支名>"))
+	}
+}
+
+func listBranches(state *ChatState) {
+	current := currentBranch(state)
+	names := []string{current}
+	for name := range state.Branches {
+		if name != current {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	fmt.Println(msg("对话分支:"))
+	for _, name := range names {
+		marker, history := " ", state.Branches[name].History
+		if name == current {
+			marker, history = "*", state.History
+		}
+		fmt.Printf(msg("  %s %s (%d 轮对话)\n"), marker, name, countUserTurns(history))
+	}
+}
+
+func switchBranch(state *ChatState, name string) {
+	if name == currentBranch(state) {
+		fmt.Printf(msg("当前已在分支 %s\n"), name)
+		return
+	}
+	target, ok := state.Branches[name]
+	if !ok {
+		fmt.Printf(msg("错误：分支 %s 不存在\n"), name)
+		return
+	}
+
+	stashBranch(state)
+	delete(state.Branches, name)
+	state.History = target.History
+	state.Pinned = target.Pinned
+	if state.Pinned == nil {
+		state.Pinned = map[int]bool{}
+	}
+	state.Branch = name
+	fmt.Printf(msg("已切换到分支 %s(%d 轮对话)\n"), name, countUserTurns(state.History))
+}
+
+// 保存会话时包含全部分支，当前分支取最新内容
+func sessionBranches(state *ChatState) map[string]Branch {
+	if len(state.Branches) == 0 {
+		return nil
+	}
+	branches := maps.Clone(state.Branches)
+	branches[currentBranch(state)] = Branch{History: state.History}
+	return branches
+}
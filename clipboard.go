@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// 按系统选择读取剪贴板的命令，Linux 下依次尝试 Wayland 和 X11 工具
+func clipboardPasteCommand() ([]string, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{"pbpaste"}, nil
+	case "windows":
+		return []string{"powershell", "-NoProfile", "-Command", "Get-Clipboard -Raw"}, nil
+	}
+
+	candidates := [][]string{
+		{"xclip", "-selection", "clipboard", "-o"},
+		{"xsel", "--clipboard", "--output"},
+	}
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		candidates = append([][]string{{"wl-paste", "--no-newline"}}, candidates...)
+	}
+	for _, args := range candidates {
+		if _, err := exec.LookPath(args[0]); err == nil {
+			return args, nil
+		}
+	}
+	return nil, errors.New(msg("未找到剪贴板工具(需要 wl-paste、xclip 或 xsel)"))
+}
+
+func readClipboard() (string, error) {
+	args, err := clipboardPasteCommand()
+	if err != nil {
+		return "", err
+	}
+	output, err := exec.Command(args[0], args[1:]...).Output()
+	if err != nil {
+		return "", fmt.Errorf(msg("读取剪贴板失败: %w"), err)
+	}
+	return string(output), nil
+}
+
+// 把剪贴板内容附加到下一条用户消息
+func attachClipboard(state *ChatState) {
+	text, err := readClipboard()
+	if err != nil {
+		fmt.Printf(msg("错误：%v\n"), err)
+		return
+	}
+	text = strings.TrimRight(text, "\r\n")
+	if strings.TrimSpace(text) == "" {
+		fmt.Println(msg("剪贴板为空"))
+		return
+	}
+	if len(text) > *maxContextBytes {
+		fmt.Printf(msg("错误：剪贴板内容 %d 字节，超出大小限制 %d 字节(-max-context-bytes)\n"), len(text), *maxContextBytes)
+		return
+	}
+
+	state.Pending = append(state.Pending, fmt.Sprintf("```\n%s\n```", text))
+	fmt.Printf(msg("已附加剪贴板内容 %d 字节，约 %d tokens(将附加到下一条消息)\n"), len(text), estimateTokens(text))
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"readline"
+)
+
+// 交互命令说明，同时用于 /help、自动补全和 --list-commands。
+// 同一命令可有多条用法，补全时合并
+type CommandSpec struct {
+	Name        string   `json:"name"`
+	Usage       string   `json:"usage"`
+	Description string   `json:"description"`
+	Subcommands []string `json:"subcommands,omitempty"`
+	ModelArg    bool     `json:"model_arg,omitempty"` // 命令及其子命令后可补全模型名
+}
+
+var commandSpecs = []CommandSpec{
+	{Name: "/help", Usage: "/help", Description: "显示本帮助"},
+	{Name: "/info", Usage: "/info", Description: "显示当前会话的完整信息"},
+	{Name: "/stats", Usage: "/stats", Description: "显示本次会话的延迟、首字时间和生成速度统计"},
+	{Name: "/cost", Usage: "/cost", Description: "按模型估算本次会话的费用(价格可在配置文件的 pricing 中修改)"},
+	{Name: "/tool-result", Usage: "/tool-result <id> <JSON>", Description: "提供工具调用的结果(需 -tools)，全部调用都有结果后自动继续回答"},
+	{Name: "/errors", Usage: "/errors [clear]", Description: "列出本次会话中失败的请求(时间、状态码、错误信息)", Subcommands: []string{"clear"}},
+	{Name: "/lint", Usage: "/lint", Description: "检查对话中的常见问题(上下文接近上限、重复消息、系统提示词为空、消息未写完)"},
+	{Name: "/benchmark", Usage: "/benchmark <次数>", Description: "用固定问题多次请求当前模型并统计速度(不影响对话历史)"},
+	{Name: "/title", Usage: "/title [标题]", Description: "自动生成或手动设置会话标题(用于提示符和会话文件名)"},
+	{Name: "/reset", Usage: "/reset", Description: "清除对话历史"},
+	{Name: "/retry", Usage: "/retry", Description: "重新生成最后一条回复"},
+	{Name: "/retry-with", Usage: "/retry-with <参数>=<值>", Description: "临时使用指定参数(temp, top_p, presence, seed, model)重新生成最后一条回复"},
+	{Name: "/diff", Usage: "/diff", Description: "逐行比较最近两次回复的差异"},
+	{Name: "/replay", Usage: "/replay [-n 轮数]", Description: "重新显示对话内容"},
+	{Name: "/replay-from", Usage: "/replay-from <序号>", Description: "丢弃该消息之后的对话，最后为用户消息时重新生成回复(不带序号时列出消息)"},
+	{Name: "/replay-last", Usage: "/replay-last <轮数>", Description: "在当前系统提示词上开始新对话，按顺序重新发送最后几轮的用户消息(可先用 /model 换模型)"},
+	{Name: "/export", Usage: "/export <md|json|csv|html> <路径>", Description: "导出对话(CSV 列: index, role, content, char_count, estimated_tokens, timestamp；html 为带样式的独立网页)", Subcommands: []string{"md", "json", "csv", "html"}},
+	{Name: "/model", Usage: "/model [模型名]", Description: "显示/切换模型", ModelArg: true},
+	{Name: "/model", Usage: "/model info [模型名]", Description: "查看模型能力(上下文长度、多模态等)", Subcommands: []string{"info"}, ModelArg: true},
+	{Name: "/pick", Usage: "/pick [关键字]", Description: "从列表中选择模型(可输入序号或名称片段模糊筛选)"},
+	{Name: "/compare", Usage: "/compare <模型名>", Description: "用另一个模型回答上一个问题并对比(不影响对话历史)", ModelArg: true},
+	{Name: "/debug", Usage: "/debug", Description: "切换调试信息"},
+	{Name: "/think", Usage: "/think", Description: "切换深度思考(enable_thinking)并显示思考过程"},
+	{Name: "/autolang", Usage: "/autolang [on|off]", Description: "查看/切换按用户消息的语言要求回复语言", Subcommands: []string{"on", "off"}},
+	{Name: "/presence", Usage: "/presence [值|off]", Description: "查看/设置存在惩罚(-2 到 2)"},
+	{Name: "/format", Usage: "/format [json|text]", Description: "查看/切换回复格式(json 时请求 json_object 格式)", Subcommands: []string{"json", "text"}},
+	{Name: "/history", Usage: "/history", Description: "查看命令历史"},
+	{Name: "/history", Usage: "/history clear", Description: "清空命令历史", Subcommands: []string{"clear"}},
+	{Name: "/history-search", Usage: "/history-search <关键字>", Description: "在命令历史(包括以往会话)中查找"},
+	{Name: "/history-run", Usage: "/history-run <序号>", Description: "重新执行命令历史中的一条"},
+	{Name: "/prompt", Usage: "/prompt save <名称>", Description: "保存当前系统提示词为模板", Subcommands: []string{"save"}},
+	{Name: "/prompt", Usage: "/prompt use <名称>", Description: "使用模板作为系统提示词", Subcommands: []string{"use"}},
+	{Name: "/prompt", Usage: "/prompt list", Description: "列出提示词模板", Subcommands: []string{"list"}},
+	{Name: "/prompt-preview", Usage: "/prompt-preview [消息]", Description: "显示实际将发送的 messages(含上下文文件、变量替换和前后缀)，不发送请求"},
+	{Name: "/overlay", Usage: "/overlay push <提示词>", Description: "在系统提示词之上叠加一层提示词(发送时作为额外的系统消息)", Subcommands: []string{"push", "pop", "list", "clear"}},
+	{Name: "/overlay", Usage: "/overlay pop|list|clear", Description: "移除最上层/列出/清除叠加的提示词"},
+	{Name: "/role", Usage: "/role <名称>", Description: "使用角色文件($HOME/.abls/roles/<名称>.md)作为系统提示词"},
+	{Name: "/role", Usage: "/role list", Description: "列出可用角色", Subcommands: []string{"list"}},
+	{Name: "/fork", Usage: "/fork [分支名]", Description: "复制当前对话为新分支并切换过去"},
+	{Name: "/branch", Usage: "/branch list", Description: "列出对话分支", Subcommands: []string{"list"}},
+	{Name: "/branch", Usage: "/branch switch <分支名>", Description: "切换到指定分支", Subcommands: []string{"switch"}},
+	{Name: "/pin", Usage: "/pin [序号]", Description: "置顶消息使其不被裁剪(不带序号时列出全部消息)"},
+	{Name: "/unpin", Usage: "/unpin <序号>", Description: "取消置顶"},
+	{Name: "/pins", Usage: "/pins", Description: "查看置顶消息"},
+	{Name: "/context", Usage: "/context add <通配符>", Description: "加载文件作为上下文(不会被裁剪)", Subcommands: []string{"add"}},
+	{Name: "/context", Usage: "/context list|clear", Description: "查看/清除上下文文件", Subcommands: []string{"list", "clear"}},
+	{Name: "/summarize", Usage: "/summarize <文件>", Description: "总结文件内容(单独请求，确认后才加入对话历史)"},
+	{Name: "/attach", Usage: "/attach", Description: "把剪贴板内容附加到下一条消息(大小受 -max-context-bytes 限制)"},
+	{Name: "/set", Usage: "/set <名称> <值>", Description: "定义变量，发送消息时替换其中的 {{名称}}"},
+	{Name: "/unset", Usage: "/unset <名称>", Description: "删除变量"},
+	{Name: "/vars", Usage: "/vars", Description: "列出变量"},
+	{Name: "/alias", Usage: "/alias <名称> <展开内容>", Description: "定义别名(展开为命令时执行，否则作为消息发送)"},
+	{Name: "/alias", Usage: "/alias list", Description: "列出别名(可在配置文件的 aliases 中预先定义)", Subcommands: []string{"list"}},
+	{Name: "/wait", Usage: "/wait <秒数>", Description: "暂停指定时间(用于脚本驱动交互会话)"},
+	{Name: "@", Usage: "@<模型名>: <消息>", Description: "只用指定模型(或模型别名)回答这一条消息，之后恢复当前模型"},
+	{Name: "!", Usage: "!<命令>", Description: "执行本地命令并把输出附加到下一条消息(需 -allow-shell)"},
+	{Name: "exit", Usage: "exit", Description: "退出程序"},
+}
+
+// 用法列的对齐宽度，超出时只留两个空格
+const helpUsageWidth = 13
+
+func commandHelpText() string {
+	var b strings.Builder
+	for _, spec := range commandSpecs {
+		usage := msg(spec.Usage)
+		pad := max(helpUsageWidth-displayWidth(usage), 2)
+		fmt.Fprintf(&b, "  %s%s%s\n", usage, strings.Repeat(" ", pad), msg(spec.Description))
+	}
+	return b.String()
+}
+
+func getCompleter() *readline.PrefixCompleter {
+	modelItems := func() []readline.PrefixCompleterInterface {
+		var items []readline.PrefixCompleterInterface
+		for _, name := range append(knownModelNames(), sortedKeys(appConfig.ModelAliases)...) {
+			items = append(items, readline.PcItem(name))
+		}
+		return items
+	}
+
+	var (
+		order     []string
+		subs      = map[string][]string{}
+		modelArgs = map[string]bool{}
+	)
+	for _, spec := range commandSpecs {
+		if !strings.HasPrefix(spec.Name, "/") && spec.Name != "exit" {
+			continue
+		}
+		if _, seen := subs[spec.Name]; !seen {
+			order = append(order, spec.Name)
+			subs[spec.Name] = nil
+		}
+		subs[spec.Name] = append(subs[spec.Name], spec.Subcommands...)
+		modelArgs[spec.Name] = modelArgs[spec.Name] || spec.ModelArg
+	}
+
+	var items []readline.PrefixCompleterInterface
+	for _, name := range order {
+		var children []readline.PrefixCompleterInterface
+		if modelArgs[name] {
+			children = modelItems()
+		}
+		for _, sub := range subs[name] {
+			var grandchildren []readline.PrefixCompleterInterface
+			if modelArgs[name] {
+				grandchildren = modelItems()
+			}
+			children = append(children, readline.PcItem(sub, grandchildren...))
+		}
+		items = append(items, readline.PcItem(name, children...))
+	}
+	return readline.NewPrefixCompleter(items...)
+}
+
+type flagSpec struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Default     string `json:"default"`
+}
+
+// --list-commands: 以JSON输出全部交互命令和命令行参数，供生成 shell 补全脚本
+func printCommandList() {
+	commands := make([]CommandSpec, len(commandSpecs))
+	for i, spec := range commandSpecs {
+		spec.Usage, spec.Description = msg(spec.Usage), msg(spec.Description)
+		commands[i] = spec
+	}
+
+	var flags []flagSpec
+	flag.VisitAll(func(f *flag.Flag) {
+		flags = append(flags, flagSpec{Name: f.Name, Description: f.Usage, Default: f.DefValue})
+	})
+
+	data, err := json.MarshalIndent(struct {
+		Commands []CommandSpec `json:"commands"`
+		Flags    []flagSpec    `json:"flags"`
+	}{commands, flags}, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, msg("JSON编码失败: %v\n"), err)
+		os.Exit(1)
+	}
+	fmt.Println(string(data))
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// 配置文件 $HOME/.abls/config.json
+type Config struct {
+	ModelProfiles map[string]ModelProfile `json:"model_profiles,omitempty"`
+	Aliases       map[string]string       `json:"aliases,omitempty"`
+	Pricing       map[string]ModelPrice   `json:"pricing,omitempty"`
+	RoleMap       map[string]string       `json:"role_map,omitempty"`      // 见 -role-map
+	ModelAliases  map[string]string       `json:"model_aliases,omitempty"` // 如 "fast": "qwen-turbo"
+}
+
+// 切换到某个模型时使用的默认采样参数，nil 表示不发送
+type ModelProfile struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+}
+
+var appConfig Config
+
+func getConfigPath() string {
+	if *configFile != "" {
+		return *configFile
+	}
+	return filepath.Join(ablsDir(), "config.json")
+}
+
+// 配置文件不存在时使用内置默认值
+func loadConfig() error {
+	data, err := os.ReadFile(getConfigPath())
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && *configFile == "" {
+			return nil
+		}
+		return err
+	}
+	if err := json.Unmarshal(data, &appConfig); err != nil {
+		return fmt.Errorf(msg("解析配置文件失败: %w"), err)
+	}
+	return nil
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+// 配置文件 model_profiles 中该模型的参数。没有内置默认值，未配置的模型不发送 temperature/top_p，
+// 由服务商使用自己的默认值
+func modelProfile(model string) ModelProfile {
+	return appConfig.ModelProfiles[model]
+}
+
+// 按当前模型设置 temperature/top_p，命令行显式指定的值优先
+func applyModelProfile(state *ChatState) {
+	profile := modelProfile(state.Model)
+	if !isFlagSet("temperature") {
+		state.Temperature = profile.Temperature
+	}
+	if !isFlagSet("top-p") {
+		state.TopP = profile.TopP
+	}
+}
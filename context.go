@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 通过 /context add 加载的文件
+type ContextDoc struct {
+	Path    string
+	Content string
+}
+
+// 组装实际发送的消息：系统提示词之后依次插入叠加的提示词、上下文文件和少样本示例，再接对话历史
+func buildMessages(state *ChatState) []Message {
+	return assembleMessages(state.History, state.Overlays, state.ContextDocs)
+}
+
+func assembleMessages(history []Message, overlays []string, docs []ContextDoc) []Message {
+	if len(history) == 0 {
+		return nil
+	}
+
+	messages := make([]Message, 0, len(history)+len(overlays)+len(docs)+len(fewShotExamples))
+	rest := history
+	if rest[0].Role == "system" {
+		messages = append(messages, rest[0])
+		rest = rest[1:]
+	}
+	for _, overlay := range overlays {
+		messages = append(messages, Message{Role: "system", Content: overlay})
+	}
+	for _, doc := range docs {
+		messages = append(messages, Message{Role: "system", Content: contextMessage(doc)})
+	}
+	messages = append(messages, fewShotExamples...)
+	return append(messages, rest...)
+}
+
+func contextMessage(doc ContextDoc) string {
+	return fmt.Sprintf("以下是文件 %s 的内容:\n```\n%s\n```", doc.Path, doc.Content)
+}
+
+func contextBytes(state *ChatState) int {
+	total := 0
+	for _, doc := range state.ContextDocs {
+		total += len(doc.Content)
+	}
+	return total
+}
+
+func handleContextCommand(input string, state *ChatState) {
+	parts := strings.Fields(input)
+	if len(parts) < 2 {
+		fmt.Println(msg("用法: /context add <通配符> | /context list | /context clear"))
+		return
+	}
+
+	switch parts[1] {
+	case "add":
+		if len(parts) < 3 {
+			fmt.Println(msg("用法: /context add <通配符>"))
+			return
+		}
+		addContextFiles(strings.Join(parts[2:], " "), state)
+	case "list":
+		listContextFiles(state)
+	case "clear":
+		state.ContextDocs = nil
+		fmt.Println(msg("已清除上下文文件"))
+	default:
+		fmt.Println(msg("错误：未知的子命令"), parts[1])
+	}
+}
+
+func addContextFiles(pattern string, state *ChatState) {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		fmt.Printf(msg("错误：无效的通配符: %v\n"), err)
+		return
+	}
+	if len(paths) == 0 {
+		fmt.Println(msg("没有匹配的文件"))
+		return
+	}
+
+	loaded := map[string]bool{}
+	for _, doc := range state.ContextDocs {
+		loaded[doc.Path] = true
+	}
+
+	total := contextBytes(state)
+	added, tokens := 0, 0
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() || loaded[path] {
+			continue
+		}
+		if total+int(info.Size()) > *maxContextBytes {
+			fmt.Printf(msg("跳过 %s：超出上下文总大小限制 %d 字节\n"), path, *maxContextBytes)
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, msg("读取 %s 失败: %v\n"), path, err)
+			continue
+		}
+
+		doc := ContextDoc{Path: path, Content: string(data)}
+		state.ContextDocs = append(state.ContextDocs, doc)
+		total += len(data)
+		tokens += estimateTokens(contextMessage(doc))
+		added++
+	}
+
+	fmt.Printf(msg("已添加 %d 个文件，约 %d tokens(上下文共 %d 字节)\n"), added, tokens, total)
+}
+
+func listContextFiles(state *ChatState) {
+	if len(state.ContextDocs) == 0 {
+		fmt.Println(msg("暂无上下文文件"))
+		return
+	}
+
+	fmt.Println(msg("上下文文件:"))
+	for _, doc := range state.ContextDocs {
+		fmt.Printf(msg("  %s (%d 字节, ~%d tokens)\n"), doc.Path, len(doc.Content), estimateTokens(doc.Content))
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// 每千 tokens 的价格(元)
+type ModelPrice struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// 内置价格仅为估算，以服务商实际计费为准，可在配置文件的 pricing 中按模型名覆盖
+var defaultPricing = map[string]ModelPrice{
+	"qwen-plus":   {Input: 0.0008, Output: 0.002},
+	"qwen-max":    {Input: 0.0024, Output: 0.0096},
+	"qwen-turbo":  {Input: 0.0003, Output: 0.0006},
+	"deepseek-r1": {Input: 0.004, Output: 0.016},
+	"deepseek-v3": {Input: 0.002, Output: 0.008},
+}
+
+func modelPrice(model string) (ModelPrice, bool) {
+	if price, ok := appConfig.Pricing[model]; ok {
+		return price, true
+	}
+	price, ok := defaultPricing[model]
+	return price, ok
+}
+
+// 累计用量，同时按模型分别记录以便估算费用
+func recordUsage(state *ChatState, model string, usage *Usage) {
+	if usage == nil {
+		return
+	}
+	state.TotalUsage.add(*usage)
+
+	if state.ModelUsage == nil {
+		state.ModelUsage = map[string]Usage{}
+	}
+	modelUsage := state.ModelUsage[model]
+	modelUsage.add(*usage)
+	state.ModelUsage[model] = modelUsage
+}
+
+// -show-usage: 每轮回复后输出本轮用量
+func printTurnUsage(usage *Usage) {
+	finishLine()
+	if usage == nil {
+		fmt.Println(colorize(ansiDim, msg("[用量未知]")))
+		return
+	}
+	fmt.Println(colorize(ansiDim, fmt.Sprintf("[↑%d ↓%d Σ%d]", usage.PromptTokens, usage.CompletionTokens, usage.TotalTokens)))
+}
+
+func showCost(state *ChatState) {
+	if len(state.ModelUsage) == 0 {
+		fmt.Println(msg("暂无用量数据"))
+		return
+	}
+
+	models := make([]string, 0, len(state.ModelUsage))
+	for model := range state.ModelUsage {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+
+	total := 0.0
+	fmt.Println(msg("费用估算:"))
+	fmt.Printf("  %-14s %10s %10s %12s\n", msg("模型"), msg("输入"), msg("输出"), msg("费用(元)"))
+	for _, model := range models {
+		usage := state.ModelUsage[model]
+		price, ok := modelPrice(model)
+		if !ok {
+			fmt.Printf("  %-14s %10d %10d %12s\n", model, usage.PromptTokens, usage.CompletionTokens, msg("未知价格"))
+			continue
+		}
+		cost := (float64(usage.PromptTokens)*price.Input + float64(usage.CompletionTokens)*price.Output) / 1000
+		total += cost
+		fmt.Printf("  %-14s %10d %10d %12.4f\n", model, usage.PromptTokens, usage.CompletionTokens, cost)
+	}
+	fmt.Printf(msg("  合计约 %.4f 元(价格为估算值，可在配置文件的 pricing 中修改)\n"), total)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// 显式声明支持的压缩格式，由客户端自行解码；未编译 Brotli 解码器，不声明 br
+const acceptEncoding = "gzip, deflate"
+
+// 根据 Content-Encoding 包装响应体
+func decodeBody(resp *http.Response) (io.Reader, error) {
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	switch encoding {
+	case "", "identity":
+		return resp.Body, nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(resp.Body)
+	case "deflate":
+		return newDeflateReader(resp.Body)
+	case "br":
+		return nil, errors.New(msg("不支持的响应编码: br(未编译 Brotli 支持，请让服务端改用 gzip 或 deflate)"))
+	default:
+		return nil, fmt.Errorf(msg("不支持的响应编码: %s"), encoding)
+	}
+}
+
+// deflate 通常带 zlib 头，部分服务端会直接发送裸 deflate 数据
+func newDeflateReader(body io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(body)
+	header, err := br.Peek(2)
+	if err != nil {
+		return nil, fmt.Errorf(msg("读取deflate数据失败: %w"), err)
+	}
+	if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
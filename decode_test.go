@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testSSE = "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"你好\"}}]}\n\n" +
+	"data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"，世界\"},\"finish_reason\":\"stop\"}]}\n\n" +
+	"data: [DONE]\n\n"
+
+func compress(t *testing.T, newWriter func(io.Writer) io.WriteCloser) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := newWriter(&buf)
+	if _, err := io.WriteString(w, testSSE); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding string
+		body     func(t *testing.T) []byte
+	}{
+		{"identity", "", func(*testing.T) []byte { return []byte(testSSE) }},
+		{"gzip", "gzip", func(t *testing.T) []byte {
+			return compress(t, func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) })
+		}},
+		{"zlib 封装的 deflate", "deflate", func(t *testing.T) []byte {
+			return compress(t, func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) })
+		}},
+		{"裸 deflate", "deflate", func(t *testing.T) []byte {
+			return compress(t, func(w io.Writer) io.WriteCloser {
+				fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+				return fw
+			})
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := tt.body(t)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.encoding != "" {
+					w.Header().Set("Content-Encoding", tt.encoding)
+				}
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.Write(body)
+			}))
+			defer server.Close()
+
+			// 与实际请求一样显式声明 Accept-Encoding，Transport 不会自动解压
+			req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			decoded, err := decodeBody(resp)
+			if err != nil {
+				t.Fatalf("decodeBody: %v", err)
+			}
+			result, err := processStreamResponse(decoded, &ChatState{}, false, func() {})
+			if err != nil {
+				t.Fatalf("processStreamResponse: %v", err)
+			}
+			if result.Content != "你好，世界" {
+				t.Errorf("Content = %q, want %q", result.Content, "你好，世界")
+			}
+		})
+	}
+}
+
+func TestDecodeBodyUnsupported(t *testing.T) {
+	for _, encoding := range []string{"zstd", "br"} {
+		resp := &http.Response{Header: http.Header{"Content-Encoding": {encoding}}, Body: http.NoBody}
+		if _, err := decodeBody(resp); err == nil {
+			t.Errorf("decodeBody 对 %s 应返回错误", encoding)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 重新生成最后一条回复，旧回复保存在 PrevReply 中供 /diff 使用
+func retryLastReply(state *ChatState) {
+	last := len(state.History) - 1
+	if last < 1 || state.History[last].Role != "assistant" {
+		fmt.Println(msg("错误：没有可重新生成的回复"))
+		return
+	}
+
+	original := state.History[last]
+	state.PrevReply = original.Content
+	state.History = state.History[:last]
+	if _, err := processAIResponse(state, streamMode(state)); err != nil {
+		finishLine()
+		fmt.Fprintf(os.Stderr, msg("错误: %v\n"), err)
+		// 放回原消息，保留时间戳和工具调用
+		state.History = append(state.History, original)
+	}
+	finishLine()
+}
+
+// /retry-with 参数=值 ...: 临时使用指定参数重新生成最后一条回复，完成后恢复原设置
+func retryWith(input string, state *ChatState) {
+	args := strings.Fields(input)[1:]
+	if len(args) == 0 {
+		fmt.Println(msg("用法: /retry-with <参数>=<值> ...(可用参数: temp, top_p, presence, seed, model)"))
+		return
+	}
+
+	model, alias, temperature, topP, penalty, seed := state.Model, state.ModelAlias, state.Temperature, state.TopP, state.PresencePenalty, state.Seed
+	defer func() {
+		state.Model, state.ModelAlias = model, alias
+		state.Temperature, state.TopP, state.PresencePenalty, state.Seed = temperature, topP, penalty, seed
+	}()
+
+	// 先切换模型再设置其他参数，显式给出的 temp、top_p 优先于模型配置
+	ordered := make([]string, 0, len(args))
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "model=") {
+			ordered = append(ordered, arg)
+		}
+	}
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "model=") {
+			ordered = append(ordered, arg)
+		}
+	}
+
+	for _, arg := range ordered {
+		name, value, ok := strings.Cut(arg, "=")
+		if !ok || value == "" {
+			fmt.Printf(msg("错误：参数格式应为 名称=值: %s\n"), arg)
+			return
+		}
+		if err := applyRetryOverride(state, name, value); err != nil {
+			fmt.Printf(msg("错误：%v\n"), err)
+			return
+		}
+	}
+	retryLastReply(state)
+}
+
+func applyRetryOverride(state *ChatState, name, value string) error {
+	if name == "model" {
+		resolved, isAlias := resolveModelAlias(value)
+		if _, ok := lookupModel(value); !ok && !isAlias {
+			return fmt.Errorf(msg("不支持的模型: %s"), value)
+		}
+		state.Model, state.ModelAlias = value, ""
+		if isAlias {
+			state.Model, state.ModelAlias = resolved, value
+		}
+		applyModelProfile(state)
+		return nil
+	}
+	if name == "seed" {
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf(msg("seed 需要整数: %s"), value)
+		}
+		state.Seed = &v
+		return nil
+	}
+
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf(msg("%s 需要数字: %s"), name, value)
+	}
+	switch name {
+	case "temp", "temperature":
+		if v < 0 || v > 2 {
+			return errors.New(msg("temperature 取值范围为 0 到 2"))
+		}
+		state.Temperature = &v
+	case "top_p", "top-p":
+		if v <= 0 || v > 1 {
+			return errors.New(msg("top_p 取值范围为大于 0 且不超过 1"))
+		}
+		state.TopP = &v
+	case "presence", "presence_penalty":
+		if !validPenalty(v) {
+			return errors.New(msg("presence_penalty 取值范围为 -2 到 2"))
+		}
+		state.PresencePenalty = &v
+	default:
+		return fmt.Errorf(msg("不支持的参数 %q，可用参数: temp, top_p, presence, seed, model"), name)
+	}
+	return nil
+}
+
+// /replay-from <序号>: 丢弃该消息之后的对话，最后一条为用户消息时重新请求回复
+func replayFrom(input string, state *ChatState) {
+	parts := strings.Fields(input)
+	if len(parts) != 2 {
+		listMessages(state)
+		return
+	}
+	index, err := strconv.Atoi(parts[1])
+	if err != nil || index < 1 || index >= len(state.History) {
+		fmt.Printf(msg("错误：无效的消息序号，可用范围 1-%d\n"), len(state.History)-1)
+		return
+	}
+
+	dropped := len(state.History) - index - 1
+	state.History = state.History[:index+1]
+	for i := range state.Pinned {
+		if i > index {
+			delete(state.Pinned, i)
+		}
+	}
+	fmt.Printf(msg("已回到消息 %d，丢弃之后的 %d 条消息\n"), index, dropped)
+
+	if state.History[index].Role != "user" {
+		return
+	}
+	if _, err := processAIResponse(state, streamMode(state)); err != nil {
+		finishLine()
+		fmt.Fprintf(os.Stderr, msg("错误: %v\n"), err)
+	}
+	finishLine()
+}
+
+// /replay-last <轮数>: 在当前系统提示词上开始新对话，按顺序重新发送最后几轮的用户消息，
+// 可配合 /model 把最近的对话换一个模型重来
+func replayLast(input string, state *ChatState) {
+	parts := strings.Fields(input)
+	if len(parts) != 2 {
+		fmt.Println(msg("用法: /replay-last <轮数>"))
+		return
+	}
+	n, err := strconv.Atoi(parts[1])
+	if err != nil || n <= 0 {
+		fmt.Println(msg("错误：轮数需要正整数"))
+		return
+	}
+
+	var questions []string
+	for _, m := range lastTurns(state.History, n) {
+		if m.Role == "user" {
+			questions = append(questions, m.Content)
+		}
+	}
+	if len(questions) == 0 {
+		fmt.Println(msg("暂无可重新发送的对话"))
+		return
+	}
+
+	system := Message{Role: "system", Content: state.SystemPrompt}
+	if len(state.History) > 0 && state.History[0].Role == "system" {
+		system = state.History[0]
+	}
+	state.History = []Message{system}
+	state.Pinned = map[int]bool{}
+	state.LastRequestID = ""
+	fmt.Printf(msg("已开始新对话，重新发送最后 %d 轮\n"), len(questions))
+
+	for _, question := range questions {
+		fmt.Println(colorize(roleColor("user"), "["+roleLabel("user")+"] ") + question)
+		state.History = append(state.History, newMessage("user", question))
+		if _, err := processAIResponse(state, streamMode(state)); err != nil {
+			finishLine()
+			fmt.Fprintf(os.Stderr, msg("错误: %v\n"), err)
+			return
+		}
+		finishLine()
+	}
+}
+
+func showReplyDiff(state *ChatState) {
+	var replies []string
+	for _, msg := range state.History {
+		if msg.Role == "assistant" {
+			replies = append(replies, msg.Content)
+		}
+	}
+
+	var before, after string
+	switch {
+	case len(replies) > 0 && state.PrevReply != "":
+		before, after = state.PrevReply, replies[len(replies)-1]
+	case len(replies) >= 2:
+		before, after = replies[len(replies)-2], replies[len(replies)-1]
+	default:
+		fmt.Println(msg("错误：至少需要两条回复才能比较"))
+		return
+	}
+
+	for _, line := range diffLines(strings.Split(before, "\n"), strings.Split(after, "\n")) {
+		switch line[0] {
+		case '+':
+			fmt.Println(colorize(ansiGreen, line))
+		case '-':
+			fmt.Println(colorize(ansiRed, line))
+		default:
+			fmt.Println(line)
+		}
+	}
+}
+
+// 基于最长公共子序列的逐行比较
+func diffLines(a, b []string) []string {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out []string
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, "  "+a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, "- "+a[i])
+			i++
+		default:
+			out = append(out, "+ "+b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		out = append(out, "- "+a[i])
+	}
+	for ; j < len(b); j++ {
+		out = append(out, "+ "+b[j])
+	}
+	return out
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// 请求体编码器，不同服务商的请求格式各自实现
+type RequestEncoder interface {
+	Encode(req StreamRequest) ([]byte, error)
+}
+
+var requestEncoders = map[string]RequestEncoder{
+	"openai":    openAIEncoder{},
+	"anthropic": anthropicEncoder{},
+}
+
+func selectedEncoder() RequestEncoder {
+	return requestEncoders[*requestFormat]
+}
+
+// 发送时使用的角色名，配置文件的 role_map 与 -role-map 合并而成，-role-map 优先。
+// 只在编码请求时替换，对话历史中始终使用标准角色名
+var roleMap = map[string]string{}
+
+func setupRoleMap() error {
+	for role, name := range appConfig.RoleMap {
+		roleMap[role] = name
+	}
+	if *roleMapFlag == "" {
+		return nil
+	}
+	for _, pair := range strings.Split(*roleMapFlag, ",") {
+		role, name, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || name == "" {
+			return fmt.Errorf(msg("-role-map 格式错误 %q，应为 标准角色=名称"), pair)
+		}
+		roleMap[role] = name
+	}
+	for role := range roleMap {
+		switch role {
+		case "system", "user", "assistant":
+		default:
+			return fmt.Errorf(msg("role_map 只能映射 system、user、assistant，不支持 %q"), role)
+		}
+	}
+	return nil
+}
+
+func mapRole(role string) string {
+	if name, ok := roleMap[role]; ok {
+		return name
+	}
+	return role
+}
+
+func mapRoles(messages []Message) []Message {
+	if len(roleMap) == 0 {
+		return messages
+	}
+	mapped := make([]Message, len(messages))
+	for i, msg := range messages {
+		mapped[i] = msg
+		mapped[i].Role = mapRole(msg.Role)
+	}
+	return mapped
+}
+
+// -merge-roles: 部分服务商拒绝连续的同角色消息(如 /context add 或手动编辑后出现的两条 user 消息)，
+// 发送前把相邻的同角色消息以换行合并。auto 时只对要求角色交替的 anthropic 格式合并
+func validMergeRolesMode() error {
+	switch *mergeRolesMode {
+	case "auto", "on", "off":
+		return nil
+	}
+	return fmt.Errorf(msg("-merge-roles 只能为 auto|on|off，不支持 %q"), *mergeRolesMode)
+}
+
+func mergeRolesEnabled() bool {
+	if *mergeRolesMode == "auto" {
+		return *requestFormat == "anthropic"
+	}
+	return *mergeRolesMode == "on"
+}
+
+func mergeRoles(messages []Message) []Message {
+	if !mergeRolesEnabled() {
+		return messages
+	}
+	var merged []Message
+	for _, msg := range messages {
+		if last := len(merged) - 1; last >= 0 && canMergeRoles(merged[last], msg) {
+			merged[last].Content += "\n" + msg.Content
+			continue
+		}
+		merged = append(merged, msg)
+	}
+	return merged
+}
+
+// 工具结果各自对应一个调用 ID，带工具调用的助手消息合并后调用会错位，都不合并
+func canMergeRoles(prev, next Message) bool {
+	return prev.Role == next.Role && prev.Role != "tool" && len(prev.ToolCalls) == 0 && len(next.ToolCalls) == 0
+}
+
+// OpenAI chat-completions 格式
+type openAIEncoder struct{}
+
+func (openAIEncoder) Encode(req StreamRequest) ([]byte, error) {
+	req.Messages = mapRoles(mergeRoles(req.Messages))
+	return json.Marshal(req)
+}
+
+// Anthropic messages 格式：系统提示词单独放在 system 字段
+type anthropicEncoder struct{}
+
+const anthropicDefaultMaxTokens = 4096
+
+type anthropicRequest struct {
+	Model     string    `json:"model"`
+	System    string    `json:"system,omitempty"`
+	Messages  []Message `json:"messages"`
+	MaxTokens int       `json:"max_tokens"`
+	Stream    bool      `json:"stream"`
+
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+
+	Metadata *anthropicMetadata `json:"metadata,omitempty"`
+}
+
+type anthropicMetadata struct {
+	UserID string `json:"user_id"`
+}
+
+// Anthropic 格式没有对应字段的选项，设置了也无法发送，每个选项只警告一次。
+// stream_options 不在此列：流式响应本身就带用量
+var (
+	warnedDroppedOptions   = map[string]bool{}
+	warnedDroppedOptionsMu sync.Mutex // -serve 时多个请求并发编码
+)
+
+func warnDroppedOptions(req StreamRequest) {
+	set := map[string]bool{
+		"n":                req.N != nil && *req.N > 1,
+		"enable_thinking":  req.EnableThinking != nil,
+		"seed":             req.Seed != nil,
+		"presence_penalty": req.PresencePenalty != nil,
+		"logprobs":         req.LogProbs != nil || req.TopLogProbs != nil,
+		"response_format":  req.ResponseFormat != nil,
+	}
+	warnedDroppedOptionsMu.Lock()
+	defer warnedDroppedOptionsMu.Unlock()
+	for _, name := range []string{"n", "enable_thinking", "seed", "presence_penalty", "logprobs", "response_format"} {
+		if set[name] && !warnedDroppedOptions[name] {
+			warnedDroppedOptions[name] = true
+			fmt.Fprintf(os.Stderr, msg("警告：anthropic 请求格式不支持 %s，该设置不会发送\n"), name)
+		}
+	}
+}
+
+func (anthropicEncoder) Encode(req StreamRequest) ([]byte, error) {
+	var (
+		system   []string
+		messages []Message
+	)
+	for _, msg := range req.Messages {
+		if msg.Role == "system" {
+			system = append(system, msg.Content)
+			continue
+		}
+		messages = append(messages, Message{Role: msg.Role, Content: msg.Content})
+	}
+	messages = mapRoles(mergeRoles(messages))
+	warnDroppedOptions(req)
+
+	var metadata *anthropicMetadata
+	if req.User != "" {
+		metadata = &anthropicMetadata{UserID: req.User}
+	}
+	return json.Marshal(anthropicRequest{
+		Model:     req.Model,
+		System:    strings.Join(system, "\n\n"),
+		Messages:  messages,
+		MaxTokens: anthropicDefaultMaxTokens,
+		Stream:    req.Stream,
+
+		Temperature: req.Temperature,
+		TopP:        req.TopP,
+
+		Metadata: metadata,
+	})
+}
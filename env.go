@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// flag.Parse 之前需要知道 -env-file，这里手动预扫描参数
+func envFileFromArgs(args []string) string {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		name := strings.TrimLeft(arg, "-")
+		if name == arg {
+			continue
+		}
+		if value, ok := strings.CutPrefix(name, "env-file="); ok {
+			return value
+		}
+		if name == "env-file" && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// 加载 .env 文件到环境变量，已存在的变量不会被覆盖
+func loadDotEnv(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf(msg("%s:%d: 格式错误，应为 KEY=VALUE"), path, lineNo)
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+
+		if _, exists := os.LookupEnv(key); !exists {
+			os.Setenv(key, value)
+		}
+	}
+	return scanner.Err()
+}
+
+func loadEnvironment() {
+	path := envFileFromArgs(os.Args[1:])
+	if path == "" {
+		if err := loadDotEnv(".env"); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, msg("加载 .env 失败: %v\n"), err)
+		}
+		return
+	}
+
+	if err := loadDotEnv(path); err != nil {
+		fmt.Fprintf(os.Stderr, msg("错误：加载环境文件失败: %v\n"), err)
+		os.Exit(1)
+	}
+}
+
+// 参数对应的环境变量名，如 -max-messages 对应 ABLS_MAX_MESSAGES
+func flagEnvName(name string) string {
+	return "ABLS_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// flag.Parse 之后用 ABLS_* 环境变量填充未在命令行指定的参数，
+// 填充后的参数视为已显式设置
+func applyEnvFlags() {
+	set := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	flag.VisitAll(func(f *flag.Flag) {
+		if set[f.Name] || f.Name == "env-file" {
+			return
+		}
+		value, ok := os.LookupEnv(flagEnvName(f.Name))
+		if !ok {
+			return
+		}
+		if err := flag.Set(f.Name, value); err != nil {
+			fmt.Fprintf(os.Stderr, msg("错误：环境变量 %s 的值无效: %v\n"), flagEnvName(f.Name), err)
+			os.Exit(1)
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 最多保留的错误记录数，超出后丢弃最早的
+const maxErrorHistory = 100
+
+// 一次失败的请求，供 /errors 排查不稳定的接口
+type ErrorRecord struct {
+	Time       time.Time
+	Model      string
+	StatusCode int // 未收到响应时为 0
+	Message    string
+}
+
+// 记录请求错误，用户中断的请求不算
+func recordError(state *ChatState, model string, result *StreamResult, err error) {
+	if err == nil || errors.Is(err, errStreamCancelled) {
+		return
+	}
+
+	record := ErrorRecord{Time: time.Now(), Model: model, Message: err.Error()}
+	var apiErr *APIError
+	switch {
+	case errors.As(err, &apiErr):
+		record.StatusCode = apiErr.StatusCode
+	case result != nil:
+		record.StatusCode = result.StatusCode
+	}
+
+	state.ErrorHistory = append(state.ErrorHistory, record)
+	if over := len(state.ErrorHistory) - maxErrorHistory; over > 0 {
+		state.ErrorHistory = state.ErrorHistory[over:]
+	}
+}
+
+// /errors [clear]: 列出本次会话中的请求错误
+func handleErrorsCommand(input string, state *ChatState) {
+	switch strings.TrimSpace(strings.TrimPrefix(input, "/errors")) {
+	case "":
+	case "clear":
+		state.ErrorHistory = nil
+		fmt.Println(msg("已清除错误记录"))
+		return
+	default:
+		fmt.Println(msg("用法: /errors [clear]"))
+		return
+	}
+
+	if len(state.ErrorHistory) == 0 {
+		fmt.Println(msg("暂无错误记录"))
+		return
+	}
+	fmt.Printf(msg("错误记录(%d 条):\n"), len(state.ErrorHistory))
+	for _, record := range state.ErrorHistory {
+		status := "-"
+		if record.StatusCode != 0 {
+			status = fmt.Sprint(record.StatusCode)
+		}
+		fmt.Printf("  %s  %-3s  %s  %s\n", record.Time.Format("15:04:05"), status, record.Model,
+			colorize(ansiRed, previewText(record.Message, 100)))
+	}
+}
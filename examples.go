@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// -examples 加载的少样本示例，插入在系统提示词和上下文文件之后、对话之前，
+// 不属于对话历史，因此 /reset 后保留且不会被裁剪
+var fewShotExamples []Message
+
+func loadExamples() error {
+	if *examplesFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(*examplesFile)
+	if err != nil {
+		return err
+	}
+
+	var examples []Message
+	if err := json.Unmarshal(data, &examples); err != nil {
+		return fmt.Errorf(msg("解析示例文件失败: %w"), err)
+	}
+	for i, example := range examples {
+		switch example.Role {
+		case "system", "user", "assistant":
+		default:
+			return fmt.Errorf(msg("示例 %d 的 role 无效: %q"), i+1, example.Role)
+		}
+		if example.Content == "" {
+			return fmt.Errorf(msg("示例 %d 的 content 为空"), i+1)
+		}
+	}
+	fewShotExamples = examples
+	return nil
+}
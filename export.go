@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+func roleLabel(role string) string {
+	switch role {
+	case "system":
+		return msg("系统")
+	case "user":
+		return msg("用户")
+	case "assistant":
+		return "AI"
+	}
+	return role
+}
+
+func roleColor(role string) string {
+	switch role {
+	case "system":
+		return ansiYellow
+	case "user":
+		return ansiCyan
+	}
+	return ansiGreen
+}
+
+// 导出和回放时跳过默认的系统提示词
+func visibleMessages(history []Message) []Message {
+	var messages []Message
+	for _, msg := range history {
+		if msg.Role == "system" && msg.Content == defaultSystemPrompt {
+			continue
+		}
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+// 保留最后 n 轮对话(以用户消息为一轮的开始)
+func lastTurns(messages []Message, n int) []Message {
+	if n <= 0 {
+		return messages
+	}
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			n--
+			if n == 0 {
+				return messages[i:]
+			}
+		}
+	}
+	return messages
+}
+
+func handleReplay(input string, state *ChatState) {
+	parts := strings.Fields(input)
+	turns := 0
+	if len(parts) == 3 && parts[1] == "-n" {
+		n, err := strconv.Atoi(parts[2])
+		if err != nil || n <= 0 {
+			fmt.Println(msg("错误：-n 需要正整数"))
+			return
+		}
+		turns = n
+	} else if len(parts) != 1 {
+		fmt.Println(msg("用法: /replay [-n 轮数]"))
+		return
+	}
+
+	messages := lastTurns(visibleMessages(state.History), turns)
+	if len(messages) == 0 {
+		fmt.Println(msg("暂无对话内容"))
+		return
+	}
+
+	for _, msg := range messages {
+		fmt.Println(colorize(roleColor(msg.Role), "["+roleLabel(msg.Role)+"]"))
+		printReply(msg.Content)
+		fmt.Println()
+	}
+}
+
+func handleExport(input string, state *ChatState) {
+	parts := strings.Fields(input)
+	if len(parts) != 3 {
+		fmt.Println(msg("用法: /export <md|json|csv|html> <路径>"))
+		return
+	}
+
+	messages := visibleMessages(state.History)
+	if len(messages) == 0 {
+		fmt.Println(msg("暂无对话内容"))
+		return
+	}
+
+	var err error
+	switch parts[1] {
+	case "md":
+		err = exportMarkdown(parts[2], messages)
+	case "json":
+		err = exportJSON(parts[2], messages)
+	case "csv":
+		err = exportCSV(parts[2], messages)
+	case "html":
+		err = exportHTML(parts[2], state.Title, messages)
+	default:
+		fmt.Printf(msg("错误：不支持的导出格式 %q，可选 md|json|csv|html\n"), parts[1])
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, msg("导出失败: %v\n"), err)
+		return
+	}
+	fmt.Printf(msg("已导出 %d 条消息到 %s\n"), len(messages), parts[2])
+}
+
+func exportMarkdown(path string, messages []Message) error {
+	var b strings.Builder
+	for _, msg := range messages {
+		fmt.Fprintf(&b, "## %s", roleLabel(msg.Role))
+		if msg.Timestamp != nil {
+			fmt.Fprintf(&b, " (%s)", msg.Timestamp.Format("2006-01-02 15:04:05"))
+		}
+		fmt.Fprintf(&b, "\n\n%s\n\n", msg.Content)
+	}
+	return os.WriteFile(path, []byte(b.String()), 0o600)
+}
+
+func exportJSON(path string, messages []Message) error {
+	data, err := json.MarshalIndent(messages, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// 列: index, role, content, char_count, estimated_tokens, timestamp(未记录时为空)
+func exportCSV(path string, messages []Message) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Write([]string{"index", "role", "content", "char_count", "estimated_tokens", "timestamp"})
+	for i, msg := range messages {
+		timestamp := ""
+		if msg.Timestamp != nil {
+			timestamp = msg.Timestamp.Format(time.RFC3339)
+		}
+		w.Write([]string{
+			strconv.Itoa(i + 1),
+			msg.Role,
+			msg.Content,
+			strconv.Itoa(utf8.RuneCountInString(msg.Content)),
+			strconv.Itoa(estimateTokens(msg.Content)),
+			timestamp,
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"html"
+	"html/template"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 导出 HTML 使用的模板，样式内联，生成的文件不依赖外部资源
+var htmlExportTemplate = template.Must(template.New("export").Parse(`<!DOCTYPE html>
+<html lang="zh">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { max-width: 860px; margin: 2em auto; padding: 0 1em; font-family: -apple-system, "Segoe UI", "PingFang SC", "Microsoft YaHei", sans-serif; line-height: 1.6; color: #222; background: #f6f7f9; }
+h1.title { font-size: 1.4em; border-bottom: 1px solid #ddd; padding-bottom: .4em; }
+.msg { margin: 1em 0; padding: .8em 1em; border-radius: 8px; background: #fff; border-left: 4px solid #999; }
+.msg.user { border-left-color: #1e88e5; background: #eef5fd; }
+.msg.assistant { border-left-color: #43a047; }
+.msg.system { border-left-color: #f9a825; background: #fffbea; font-size: .92em; }
+.role { font-weight: bold; font-size: .85em; color: #555; margin-bottom: .3em; }
+.time { font-weight: normal; color: #999; margin-left: .5em; }
+pre { background: #272822; color: #f8f8f2; padding: .8em; border-radius: 6px; overflow-x: auto; }
+code { font-family: Menlo, Consolas, monospace; font-size: .9em; }
+:not(pre) > code { background: #eceff1; padding: .1em .3em; border-radius: 3px; }
+footer { margin-top: 2em; font-size: .8em; color: #999; }
+</style>
+</head>
+<body>
+<h1 class="title">{{.Title}}</h1>
+{{range .Messages}}<div class="msg {{.Role}}">
+<div class="role">{{.Label}}{{if .Time}}<span class="time">{{.Time}}</span>{{end}}</div>
+{{.Body}}
+</div>
+{{end}}<footer>导出于 {{.ExportedAt}}</footer>
+</body>
+</html>
+`))
+
+type htmlMessage struct {
+	Role, Label, Time string
+	Body              template.HTML
+}
+
+func exportHTML(path, title string, messages []Message) error {
+	if title == "" {
+		title = msg("对话记录")
+	}
+	data := struct {
+		Title, ExportedAt string
+		Messages          []htmlMessage
+	}{Title: title, ExportedAt: time.Now().Format("2006-01-02 15:04:05")}
+
+	for _, msg := range messages {
+		m := htmlMessage{Role: msg.Role, Label: roleLabel(msg.Role), Body: template.HTML(markdownToHTML(msg.Content))}
+		if msg.Timestamp != nil {
+			m.Time = msg.Timestamp.Format("2006-01-02 15:04:05")
+		}
+		data.Messages = append(data.Messages, m)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := htmlExportTemplate.Execute(f, data); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+var (
+	mdHeading     = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	mdBulletItem  = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	mdOrderedItem = regexp.MustCompile(`^\s*\d+[.)]\s+(.*)$`)
+	mdBold        = regexp.MustCompile(`\*\*(.+?)\*\*`)
+	mdLink        = regexp.MustCompile(`\[([^\]]+)\]\((https?://[^)\s]+)\)`)
+)
+
+// 简单的 Markdown 转换，支持代码块、标题、有序/无序列表、段落、行内代码、粗体和链接，
+// 其余内容按纯文本转义
+func markdownToHTML(text string) string {
+	var (
+		b         strings.Builder
+		paragraph []string
+		list      string // 当前列表标签 ul/ol，空表示不在列表中
+		inCode    bool
+	)
+	closeParagraph := func() {
+		if len(paragraph) > 0 {
+			b.WriteString("<p>" + strings.Join(paragraph, "<br>\n") + "</p>\n")
+			paragraph = nil
+		}
+	}
+	closeList := func() {
+		if list != "" {
+			b.WriteString("</" + list + ">\n")
+			list = ""
+		}
+	}
+	openList := func(tag string) {
+		closeParagraph()
+		if list != tag {
+			closeList()
+			b.WriteString("<" + tag + ">\n")
+			list = tag
+		}
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			if inCode {
+				b.WriteString("</code></pre>\n")
+			} else {
+				closeParagraph()
+				closeList()
+				b.WriteString("<pre><code>")
+			}
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			b.WriteString(html.EscapeString(line) + "\n")
+			continue
+		}
+
+		if m := mdHeading.FindStringSubmatch(trimmed); m != nil {
+			closeParagraph()
+			closeList()
+			tag := "h" + string(rune('0'+len(m[1])))
+			b.WriteString("<" + tag + ">" + inlineMarkdown(m[2]) + "</" + tag + ">\n")
+		} else if m := mdBulletItem.FindStringSubmatch(line); m != nil {
+			openList("ul")
+			b.WriteString("<li>" + inlineMarkdown(m[1]) + "</li>\n")
+		} else if m := mdOrderedItem.FindStringSubmatch(line); m != nil {
+			openList("ol")
+			b.WriteString("<li>" + inlineMarkdown(m[1]) + "</li>\n")
+		} else if trimmed == "" {
+			closeParagraph()
+			closeList()
+		} else {
+			closeList()
+			paragraph = append(paragraph, inlineMarkdown(trimmed))
+		}
+	}
+	if inCode {
+		b.WriteString("</code></pre>\n")
+	}
+	closeParagraph()
+	closeList()
+	return b.String()
+}
+
+// 行内代码原样转义，其余部分处理粗体和 http(s) 链接，未配对的反引号保持原样
+func inlineMarkdown(text string) string {
+	plain := func(s string) string {
+		s = mdBold.ReplaceAllString(html.EscapeString(s), "<strong>$1</strong>")
+		return mdLink.ReplaceAllString(s, `<a href="$2">$1</a>`)
+	}
+
+	var b strings.Builder
+	parts := strings.Split(text, "`")
+	for i, part := range parts {
+		switch {
+		case i%2 == 1 && i < len(parts)-1:
+			b.WriteString("<code>" + html.EscapeString(part) + "</code>")
+		case i%2 == 1:
+			b.WriteString("`" + plain(part))
+		default:
+			b.WriteString(plain(part))
+		}
+	}
+	return b.String()
+}
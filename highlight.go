@@ -0,0 +1,98 @@
+package main
+
+import (
+	"io"
+	"strings"
+)
+
+// -highlight: 流式输出时为 ``` 代码块着色(代码青色、分隔符行暗色)。
+// 数据块可能在任意位置断开，行首可能是分隔符的内容先缓存，确定后再输出
+type highlightWriter struct {
+	w       io.Writer
+	inCode  bool
+	mode    int             // 当前行的状态，见下方常量
+	pending strings.Builder // lineStart 状态下尚未确定的行首内容
+
+	out      strings.Builder
+	run      strings.Builder
+	runColor string
+}
+
+const (
+	lineStart = iota // 行首，还不能确定是否为分隔符行
+	lineText         // 普通行
+	lineFence        // 分隔符行的剩余部分(如语言标记)
+)
+
+func (h *highlightWriter) Write(p []byte) (int, error) {
+	for _, r := range string(p) {
+		switch h.mode {
+		case lineFence:
+			h.add(ansiDim, string(r))
+		case lineText:
+			h.add(h.textColor(), string(r))
+		default:
+			h.pending.WriteRune(r)
+			head := strings.TrimLeft(h.pending.String(), " \t")
+			switch {
+			case strings.HasPrefix(head, "```"):
+				h.add(ansiDim, h.pending.String())
+				h.pending.Reset()
+				h.inCode = !h.inCode
+				h.mode = lineFence
+			case r == '\n' || !strings.HasPrefix("```", head):
+				h.add(h.textColor(), h.pending.String())
+				h.pending.Reset()
+				h.mode = lineText
+			}
+		}
+		if r == '\n' {
+			h.mode = lineStart
+		}
+	}
+	return len(p), h.flush()
+}
+
+// 每轮回复结束时输出缓存内容并重置状态，未闭合的代码块不影响下一轮
+func (h *highlightWriter) EndTurn() error {
+	h.add(h.textColor(), h.pending.String())
+	h.pending.Reset()
+	h.inCode = false
+	h.mode = lineStart
+	return h.flush()
+}
+
+func (h *highlightWriter) textColor() string {
+	if h.inCode {
+		return ansiCyan
+	}
+	return ""
+}
+
+// 相同颜色的连续内容合并后再加转义序列
+func (h *highlightWriter) add(color, s string) {
+	if color != h.runColor {
+		h.endRun()
+		h.runColor = color
+	}
+	h.run.WriteString(s)
+}
+
+func (h *highlightWriter) endRun() {
+	if h.run.Len() == 0 {
+		return
+	}
+	if h.runColor == "" {
+		h.out.WriteString(h.run.String())
+	} else {
+		h.out.WriteString(colorize(h.runColor, h.run.String()))
+	}
+	h.run.Reset()
+}
+
+func (h *highlightWriter) flush() error {
+	h.endRun()
+	_, err := io.WriteString(h.w, h.out.String())
+	h.out.Reset()
+	return err
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 持久化的命令历史条目
+type CmdEntry struct {
+	Time    time.Time `json:"time"`
+	Command string    `json:"command"`
+}
+
+// 本地数据目录 $HOME/.abls
+func ablsDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), ".abls")
+	}
+	return filepath.Join(home, ".abls")
+}
+
+func getCmdHistoryPath() string {
+	if *cmdHistoryFile != "" {
+		return *cmdHistoryFile
+	}
+	return filepath.Join(ablsDir(), "cmd_history.json")
+}
+
+// 命令历史文件最多保存的条数，超出时丢弃最早的
+const maxCmdHistory = 1000
+
+// 命令历史文件存在但未能读取时置位，退出时不保存，避免用空历史覆盖原文件
+var cmdHistoryLoadFailed bool
+
+// 文件损坏时改名为 .bak 保留，之后从空历史开始
+func loadCmdHistory() ([]CmdEntry, error) {
+	path := getCmdHistoryPath()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []CmdEntry{}, nil
+		}
+		cmdHistoryLoadFailed = true
+		return nil, err
+	}
+
+	var entries []CmdEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		if renameErr := os.Rename(path, path+".bak"); renameErr != nil {
+			cmdHistoryLoadFailed = true
+			return nil, fmt.Errorf(msg("解析命令历史失败: %w(备份失败: %v，本次不会保存命令历史)"), err, renameErr)
+		}
+		return nil, fmt.Errorf(msg("解析命令历史失败: %w(原文件已改名为 %s)"), err, path+".bak")
+	}
+	return entries, nil
+}
+
+func saveCmdHistory(entries []CmdEntry) error {
+	if cmdHistoryLoadFailed {
+		return errors.New(msg("命令历史文件未能读取，不覆盖"))
+	}
+	path := getCmdHistoryPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	if len(entries) > maxCmdHistory {
+		entries = entries[len(entries)-maxCmdHistory:]
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+func recordCommand(state *ChatState, cmd string) {
+	state.CmdHistory = append(state.CmdHistory, CmdEntry{Time: time.Now(), Command: cmd})
+}
+
+func handleHistoryCommand(input string, state *ChatState) {
+	args := strings.TrimSpace(strings.TrimPrefix(input, "/history"))
+	switch args {
+	case "":
+		showCommandHistory(state)
+	case "clear":
+		state.CmdHistory = []CmdEntry{}
+		if err := saveCmdHistory(state.CmdHistory); err != nil {
+			fmt.Fprintf(os.Stderr, msg("清空命令历史失败: %v\n"), err)
+			return
+		}
+		fmt.Println(msg("命令历史已清空"))
+	default:
+		fmt.Println(msg("用法: /history [clear]"))
+	}
+}
+
+// /history-search <关键字>: 在持久化的命令历史(包括以往会话)中查找，不区分大小写
+func searchCommandHistory(input string, state *ChatState) {
+	term := strings.TrimSpace(strings.TrimPrefix(input, "/history-search"))
+	if term == "" {
+		fmt.Println(msg("用法: /history-search <关键字>"))
+		return
+	}
+
+	found := 0
+	lower := strings.ToLower(term)
+	for i, entry := range state.CmdHistory {
+		if strings.Contains(strings.ToLower(entry.Command), lower) {
+			printHistoryEntry(i, entry)
+			found++
+		}
+	}
+	if found == 0 {
+		fmt.Println(msg("没有匹配的命令"))
+		return
+	}
+	fmt.Println(msg("(可用 /history-run <序号> 重新执行)"))
+}
+
+// /history-run <序号>: 重新执行命令历史中的一条，序号与 /history 和 /history-search 一致
+func rerunCommandHistory(input string, state *ChatState) {
+	parts := strings.Fields(input)
+	if len(parts) != 2 {
+		fmt.Println(msg("用法: /history-run <序号>"))
+		return
+	}
+	n, err := strconv.Atoi(parts[1])
+	if err != nil || n < 1 || n > len(state.CmdHistory) {
+		fmt.Printf(msg("错误：无效的序号，可用范围 1-%d\n"), len(state.CmdHistory))
+		return
+	}
+	cmd := state.CmdHistory[n-1].Command
+	if strings.HasPrefix(cmd, "/history-run") {
+		fmt.Println(msg("错误：不能重新执行 /history-run"))
+		return
+	}
+
+	fmt.Println("> " + cmd)
+	if !state.isSingleCmd {
+		processInput(state, cmd)
+		return
+	}
+	if err := executeSingleCommand(state, cmd); err != nil {
+		finishLine()
+		fmt.Fprintf(os.Stderr, msg("错误: %v\n"), err)
+	}
+}
+
+func printHistoryEntry(i int, entry CmdEntry) {
+	fmt.Printf("%4d: [%s] %s\n", i+1, entry.Time.Format("2006-01-02 15:04:05"), entry.Command)
+}
+
+func showCommandHistory(state *ChatState) {
+	if len(state.CmdHistory) == 0 {
+		fmt.Println(msg("暂无历史记录"))
+		return
+	}
+
+	fmt.Println(msg("命令历史:"))
+	for i, entry := range state.CmdHistory {
+		printHistoryEntry(i, entry)
+	}
+}
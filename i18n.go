@@ -0,0 +1,580 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// 界面语言，由 setupLanguage 根据 -lang 或 LANG 等环境变量设置
+var uiLang = "zh"
+
+// 按 -lang 设置界面语言，未指定时根据 LC_ALL/LC_MESSAGES/LANG 判断，默认中文
+func setupLanguage() {
+	lang := *langFlag
+	if lang == "" {
+		for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+			if value := os.Getenv(name); value != "" {
+				lang = value
+				break
+			}
+		}
+		lang = strings.ToLower(lang)
+		if strings.HasPrefix(lang, "en") {
+			lang = "en"
+		} else {
+			lang = "zh"
+		}
+	}
+
+	switch lang {
+	case "zh", "en":
+		uiLang = lang
+	default:
+		fmt.Fprintf(os.Stderr, "错误：不支持的语言 %q，可选 zh|en\n", lang)
+		os.Exit(1)
+	}
+}
+
+// 界面文本以中文原文为键，其他语言缺少译文时显示原文
+func msg(key string) string {
+	if uiLang == "en" {
+		if text, ok := enMessages[key]; ok {
+			return text
+		}
+	}
+	return key
+}
+
+var enMessages = map[string]string{
+	// 启动与配置
+	"加载配置文件失败: %v\n":                       "Failed to load config file: %v\n",
+	"错误：%v\n":                              "Error: %v\n",
+	"错误: %v\n":                             "Error: %v\n",
+	"错误:":                                  "Error:",
+	"加载命令历史失败: %v\n":                       "Failed to load command history: %v\n",
+	"保存命令历史失败: %v\n":                       "Failed to save command history: %v\n",
+	"错误：必须提供API密钥":                         "Error: an API key is required",
+	"错误：-presence-penalty 取值范围为 -2 到 2":    "Error: -presence-penalty must be between -2 and 2",
+	"错误：-temperature 取值范围为 0 到 2":          "Error: -temperature must be between 0 and 2",
+	"错误：-top-p 取值范围为大于 0 且不超过 1":           "Error: -top-p must be greater than 0 and at most 1",
+	"错误：-top-logprobs 取值范围为 0 到 20":        "Error: -top-logprobs must be between 0 and 20",
+	"错误：不支持的请求格式 %q，可选 openai|anthropic\n": "Error: unsupported request format %q, expected openai|anthropic\n",
+	"错误：无效的API地址 %q，需要以 http:// 或 https:// 开头并包含主机名\n": "Error: invalid API address %q, it must start with http:// or https:// and include a host\n",
+	"错误：API地址不可达: %v\n": "Error: API address unreachable: %v\n",
+	"警告：API密钥格式与 %s 的常见格式不符，请检查是否有误(可用 -no-key-check 跳过)\n": "Warning: the API key does not match the usual format for %s, please check it (use -no-key-check to skip)\n",
+	"不支持的 -banner 取值 %q，可选 off|default|custom":              "unsupported -banner value %q, expected off|default|custom",
+	"-banner custom 需要同时指定 -banner-file":                    "-banner custom requires -banner-file",
+	"读取欢迎信息模板失败，使用默认模板: %v\n":                               "Failed to read banner template, using the default: %v\n",
+	"初始化命令行失败: %v\n":                                        "Failed to initialise the line editor: %v\n",
+	"读取输入错误: %v\n":                                          "Error reading input: %v\n",
+	defaultBanner:                                           enBanner,
+
+	// 交互命令
+	"空命令":                   "empty command",
+	"对话历史已重置":               "Conversation history reset",
+	"当前模型: %s\n可用模型: %s\n":  "Current model: %s\nAvailable models: %s\n",
+	"模型别名: %s\n":            "Model aliases: %s\n",
+	"错误：不支持的模型":             "Error: unsupported model",
+	"已切换模型为: %s\n":          "Switched model to: %s\n",
+	"调试模式 %v\n":             "Debug mode %v\n",
+	"深度思考 %v\n":             "Deep thinking %v\n",
+	"存在惩罚: 未设置":             "Presence penalty: not set",
+	"存在惩罚: %g\n":            "Presence penalty: %g\n",
+	"已取消存在惩罚":               "Presence penalty cleared",
+	"错误：存在惩罚取值范围为 -2 到 2":   "Error: presence penalty must be between -2 and 2",
+	"已设置存在惩罚: %g\n":         "Presence penalty set to: %g\n",
+	"回复格式: json":            "Reply format: json",
+	"回复格式: text":            "Reply format: text",
+	"用法: /format json|text": "Usage: /format json|text",
+	"已设置回复格式: %s\n":         "Reply format set to: %s\n",
+	"用法: /wait <秒数>":        "Usage: /wait <seconds>",
+	"错误：等待时间必须为正数":          "Error: the wait time must be positive",
+	"交互命令:":                 "Interactive commands:",
+	helpFooter:              enHelpFooter,
+
+	// 请求与回复
+	"警告：JSON模式下的回复不是合法JSON":     "Warning: the reply in JSON mode is not valid JSON",
+	"(已将回答 1 加入对话历史)":           "(answer 1 was added to the conversation history)",
+	"--- 回答 %d ---\n":           "--- Answer %d ---\n",
+	"[流中断，第 %d 次续传] %v\n":       "[stream interrupted, resume attempt %d] %v\n",
+	"JSON编码失败: %v\n":            "JSON encoding failed: %v\n",
+	"JSON编码失败: %w":              "JSON encoding failed: %w",
+	"生成 Idempotency-Key 失败: %w": "Failed to generate the Idempotency-Key: %w",
+	"创建请求失败: %w":                "Failed to create request: %w",
+	"请求体 %d 字节超出限制 %d 字节(-max-request-bytes)，可用 /context clear 移除上下文文件、/summarize 代替完整文件或 /reset 清除历史": "request body of %d bytes exceeds the %d byte limit (-max-request-bytes); use /context clear to drop context files, /summarize instead of full files, or /reset to clear the history",
+	"请求发送失败: %w":      "Failed to send request: %w",
+	"[思考] ":           "[thinking] ",
+	"未收到有效回复内容":       "No valid reply content received",
+	"解析JSON失败: %w":    "Failed to parse JSON: %w",
+	"服务端返回错误(%s): %s": "The server returned an error (%s): %s",
+	"警告：anthropic 请求格式不支持 %s，该设置不会发送\n": "Warning: the anthropic request format does not support %s, the setting is not sent\n",
+
+	// 角色与提示词模板
+	"当前未使用角色":                     "No role in use",
+	"当前角色: %s\n":                  "Current role: %s\n",
+	"用法: /role <名称> | /role list": "Usage: /role <name> | /role list",
+	"错误：无效的角色名称":                  "Error: invalid role name",
+	"已切换角色: %s\n":                 "Switched role to: %s\n",
+	"错误：角色 %s 不存在，可用角色: %s":       "Error: role %s does not exist, available roles: %s",
+	"读取角色失败: %w":                  "Failed to read role: %w",
+	"暂无角色，可在 %s 中添加 <名称>.md\n":    "No roles yet, add <name>.md under %s\n",
+	"角色:":          "Roles:",
+	"无(角色文件放在 %s)": "none (put role files in %s)",
+	"用法: /prompt save <名称> | /prompt use <名称> | /prompt list": "Usage: /prompt save <name> | /prompt use <name> | /prompt list",
+	"用法: /prompt %s <名称>\n":                                   "Usage: /prompt %s <name>\n",
+	"错误：未知的子命令":                                               "Error: unknown subcommand",
+	"错误：当前没有系统提示词":                                            "Error: there is no system prompt",
+	"创建模板目录失败: %v\n":                                          "Failed to create the template directory: %v\n",
+	"保存模板失败: %v\n":                                            "Failed to save template: %v\n",
+	"已保存提示词模板: %s\n":                                          "Saved prompt template: %s\n",
+	"错误：模板 %s 不存在\n":                                          "Error: template %s does not exist\n",
+	"读取模板失败: %v\n":                                            "Failed to read template: %v\n",
+	"已使用提示词模板: %s\n":                                          "Using prompt template: %s\n",
+	"暂无提示词模板":                                                 "No prompt templates yet",
+	"提示词模板:":                                                  "Prompt templates:",
+	"用法: /overlay push <提示词> | /overlay pop | /overlay list | /overlay clear": "Usage: /overlay push <prompt> | /overlay pop | /overlay list | /overlay clear",
+	"用法: /overlay push <提示词>":                                                 "Usage: /overlay push <prompt>",
+	"已叠加提示词，当前共 %d 层\n":                                                       "Prompt layered, %d layer(s) in total\n",
+	"没有叠加的提示词":                                                                "No layered prompts",
+	"已移除: %s\n":                                                               "Removed: %s\n",
+	"已清除全部叠加的提示词":                                                             "Cleared all layered prompts",
+	"叠加的提示词(由下到上):":                                                           "Layered prompts (bottom to top):",
+
+	// 命令历史
+	"解析命令历史失败: %w(备份失败: %v，本次不会保存命令历史)": "failed to parse the command history: %w (backup failed: %v, the command history will not be saved this time)",
+	"解析命令历史失败: %w(原文件已改名为 %s)":          "failed to parse the command history: %w (the original file was renamed to %s)",
+	"命令历史文件未能读取，不覆盖":                    "the command history file could not be read, not overwriting it",
+	"清空命令历史失败: %v\n":                    "Failed to clear the command history: %v\n",
+	"命令历史已清空":                           "Command history cleared",
+	"用法: /history [clear]":              "Usage: /history [clear]",
+	"用法: /history-search <关键字>":         "Usage: /history-search <term>",
+	"没有匹配的命令":                           "No matching commands",
+	"(可用 /history-run <序号> 重新执行)":       "(re-run one with /history-run <index>)",
+	"用法: /history-run <序号>":             "Usage: /history-run <index>",
+	"错误：无效的序号，可用范围 1-%d\n":              "Error: invalid index, valid range 1-%d\n",
+	"错误：不能重新执行 /history-run":            "Error: /history-run cannot be re-run",
+	"暂无历史记录":                            "No history yet",
+	"命令历史:":                             "Command history:",
+
+	// 会话与分支
+	"删除旧会话失败: %v\n":       "Failed to delete an old session: %v\n",
+	"保存会话失败: %v\n":        "Failed to save the session: %v\n",
+	"没有可恢复的会话":            "No session to restore",
+	"恢复会话失败: %v\n":        "Failed to restore the session: %v\n",
+	"已恢复 %d 轮对话(%s)\n":    "Restored %d turn(s) (%s)\n",
+	"解析会话文件失败: %w":        "failed to parse the session file: %w",
+	"会话文件为空: %s":          "session file is empty: %s",
+	"收到信号 %v，正在保存对话...\n": "Received signal %v, saving the conversation...\n",
+	"对话仍在处理中，未能自动保存":      "The conversation is still being processed, autosave skipped",
+	"自动保存失败: %v\n":        "Autosave failed: %v\n",
+	"对话已保存到 %s\n":         "Conversation saved to %s\n",
+	"读取自动保存失败: %v\n":      "Failed to read the autosave: %v\n",
+	"检测到 %s 自动保存的对话(%d 条消息)，是否恢复? [y/N] ": "Found a conversation autosaved at %s (%d messages), restore it? [y/N] ",
+	"已恢复 %d 条消息\n":                            "Restored %d messages\n",
+	"用法: /fork [分支名]":                         "Usage: /fork [branch]",
+	"错误：分支 %s 已存在\n":                          "Error: branch %s already exists\n",
+	"已从分支 %s 创建并切换到分支 %s(%d 轮对话)\n":           "Created branch %s from %s and switched to it (%d turns)\n",
+	"用法: /branch list | /branch switch <分支名>": "Usage: /branch list | /branch switch <branch>",
+	"对话分支:":                                   "Conversation branches:",
+	"  %s %s (%d 轮对话)\n":                      "  %s %s (%d turns)\n",
+	"当前已在分支 %s\n":                             "Already on branch %s\n",
+	"错误：分支 %s 不存在\n":                          "Error: branch %s does not exist\n",
+	"已切换到分支 %s(%d 轮对话)\n":                     "Switched to branch %s (%d turns)\n",
+
+	// 重新生成与回退
+	"错误：没有可重新生成的回复":                                                          "Error: there is no reply to regenerate",
+	"用法: /retry-with <参数>=<值> ...(可用参数: temp, top_p, presence, seed, model)": "Usage: /retry-with <param>=<value> ... (params: temp, top_p, presence, seed, model)",
+	"错误：参数格式应为 名称=值: %s\n":                                                   "Error: parameters must be name=value: %s\n",
+	"不支持的模型: %s":                                                             "unsupported model: %s",
+	"seed 需要整数: %s":                                                          "seed must be an integer: %s",
+	"%s 需要数字: %s":                                                            "%s must be a number: %s",
+	"temperature 取值范围为 0 到 2":                                                "temperature must be between 0 and 2",
+	"top_p 取值范围为大于 0 且不超过 1":                                                 "top_p must be greater than 0 and at most 1",
+	"presence_penalty 取值范围为 -2 到 2":                                          "presence_penalty must be between -2 and 2",
+	"不支持的参数 %q，可用参数: temp, top_p, presence, seed, model":                     "unsupported parameter %q, available: temp, top_p, presence, seed, model",
+	"错误：无效的消息序号，可用范围 1-%d\n":                                                 "Error: invalid message index, valid range 1-%d\n",
+	"已回到消息 %d，丢弃之后的 %d 条消息\n":                                                "Back at message %d, discarded the %d messages after it\n",
+	"用法: /replay-last <轮数>":                                                  "Usage: /replay-last <turns>",
+	"错误：轮数需要正整数":                                                             "Error: the number of turns must be a positive integer",
+	"暂无可重新发送的对话":                                                             "No conversation to re-send",
+	"已开始新对话，重新发送最后 %d 轮\n":                                                   "Started a new conversation, re-sending the last %d turn(s)\n",
+	"错误：至少需要两条回复才能比较":                                                        "Error: at least two replies are needed for a comparison",
+
+	// 上下文文件与变量
+	"用法: /context add <通配符> | /context list | /context clear": "Usage: /context add <glob> | /context list | /context clear",
+	"用法: /context add <通配符>":                                  "Usage: /context add <glob>",
+	"已清除上下文文件":                                                "Context files cleared",
+	"错误：无效的通配符: %v\n":                                         "Error: invalid glob: %v\n",
+	"没有匹配的文件":                                                 "No matching files",
+	"跳过 %s：超出上下文总大小限制 %d 字节\n":                                "Skipping %s: exceeds the total context limit of %d bytes\n",
+	"读取 %s 失败: %v\n":                                          "Failed to read %s: %v\n",
+	"已添加 %d 个文件，约 %d tokens(上下文共 %d 字节)\n":                    "Added %d file(s), about %d tokens (%d bytes of context in total)\n",
+	"暂无上下文文件":                                                 "No context files",
+	"上下文文件:":                                                  "Context files:",
+	"  %s (%d 字节, ~%d tokens)\n":                              "  %s (%d bytes, ~%d tokens)\n",
+	"警告：未定义的变量: %s\n":                                         "Warning: undefined variables: %s\n",
+	"用法: /set <名称> <值>":                                       "Usage: /set <name> <value>",
+	"错误：变量名只能包含字母、数字和下划线，且不能以数字开头": "Error: variable names may only contain letters, digits and underscores and must not start with a digit",
+	"已设置变量 %s\n":      "Set variable %s\n",
+	"用法: /unset <名称>": "Usage: /unset <name>",
+	"错误：变量 %s 未定义\n":  "Error: variable %s is not defined\n",
+	"已删除变量 %s\n":      "Deleted variable %s\n",
+	"暂无变量":            "No variables",
+	"变量:":             "Variables:",
+
+	// 模型
+	"模型: %s\n  上下文长度: unknown\n  多模态: unknown\n  流式输出: unknown\n": "Model: %s\n  Context length: unknown\n  Multimodal: unknown\n  Streaming: unknown\n",
+	"模型: %s\n  上下文长度: %d tokens\n  多模态: %s\n  流式输出: %s\n":         "Model: %s\n  Context length: %d tokens\n  Multimodal: %s\n  Streaming: %s\n",
+	"支持":                 "yes",
+	"不支持":                "no",
+	"用法: /compare <模型名>": "Usage: /compare <model>",
+	"错误：还没有可对比的用户消息":       "Error: there is no user message to compare yet",
+	"=== %s (当前) ===\n":    "=== %s (current) ===\n",
+	"错误：/pick 仅在交互模式下可用":   "Error: /pick is only available in interactive mode",
+	"没有匹配 %q 的模型\n":        "No model matches %q\n",
+	"选择模型(序号或名称片段，回车取消): ": "Pick a model (number or part of a name, Enter to cancel): ",
+	"错误：序号超出范围":            "Error: index out of range",
+
+	// 工具调用
+	"-tools 只支持 openai 请求格式":                                                "-tools only supports the openai request format",
+	"解析工具定义失败，应为 JSON 数组: %w":                                               "failed to parse the tool definitions, expected a JSON array: %w",
+	"工具 %d 格式无效，应为 {\"type\": \"function\", \"function\": {\"name\": ...}}": "tool %d is invalid, expected {\"type\": \"function\", \"function\": {\"name\": ...}}",
+	"[工具调用] %s(%s) id=%s":                                                   "[tool call] %s(%s) id=%s",
+	"(用 /tool-result <id> <JSON> 提供结果，全部提供后自动继续)":                           "(provide results with /tool-result <id> <JSON>; the reply continues once all are provided)",
+	"用法: /tool-result <id> <JSON>":                                          "Usage: /tool-result <id> <JSON>",
+	"错误：没有等待结果的工具调用 %q\n":                                                   "Error: no tool call %q is waiting for a result\n",
+	"错误：工具结果不是合法的 JSON":                                                     "Error: the tool result is not valid JSON",
+	"已添加工具结果，还有 %d 个调用等待结果\n":                                               "Tool result added, %d call(s) still waiting for results\n",
+
+	// 错误记录
+	"已清除错误记录":             "Error log cleared",
+	"用法: /errors [clear]": "Usage: /errors [clear]",
+	"暂无错误记录":              "No errors recorded",
+	"错误记录(%d 条):\n":       "Errors (%d):\n",
+
+	// 系统密钥库
+	"系统密钥库不可用":      "the OS keychain is unavailable",
+	"security 执行失败": "security failed",
+	"系统密钥库中没有 %s 的API密钥，请先运行 abls login": "the OS keychain has no API key for %s, run abls login first",
+	"写入密钥失败: %w": "failed to write the secret: %w",
+	"%w: 未找到 %s(macOS 使用 security，Linux 需要 secret-tool，Windows 需要 PowerShell)": "%w: %s not found (macOS uses security, Linux needs secret-tool, Windows needs PowerShell)",
+	"%s 的API密钥: ":         "API key for %s: ",
+	"错误：读取API密钥失败: %v\n":  "Error: failed to read the API key: %v\n",
+	"错误：API密钥为空":          "Error: the API key is empty",
+	"错误：保存到系统密钥库失败: %v\n": "Error: failed to save to the OS keychain: %v\n",
+	"已将 %s 的API密钥保存到系统密钥库，运行时加 -keychain 读取\n": "Saved the API key for %s to the OS keychain; run with -keychain to use it\n",
+	"警告：从系统密钥库读取API密钥失败: %v\n":                 "Warning: failed to read the API key from the OS keychain: %v\n",
+
+	// 别名与自动语言
+	"[DEBUG] 别名 %s 展开为: %q\n":              "[DEBUG] alias %s expands to: %q\n",
+	"用法: /alias <名称> <展开内容> | /alias list": "Usage: /alias <name> <expansion> | /alias list",
+	"错误：别名不能以 / 开头或与内置命令同名":                "Error: an alias must not start with / or shadow a built-in command",
+	"已设置别名: %s => %s\n":                    "Alias set: %s => %s\n",
+	"暂无别名(可在配置文件的 aliases 中定义)":            "No aliases yet (define them under aliases in the config file)",
+	"别名:":                    "Aliases:",
+	"用法: /autolang [on|off]": "Usage: /autolang [on|off]",
+	"自动回复语言: 开启":             "Automatic reply language: on",
+	"自动回复语言: 关闭":             "Automatic reply language: off",
+
+	// 请求与响应
+	"API错误 %d: %s: %s":         "API error %d: %s: %s",
+	"API错误 %d: %s":             "API error %d: %s",
+	"%v\n模型 %s 不存在或已下线":        "%v\nmodel %s does not exist or has been retired",
+	"，可改用: ":                   ", try instead: ",
+	"(交互模式下用 /model <模型名> 切换)": " (switch with /model <model> in interactive mode)",
+	"不支持的响应编码: %s":             "unsupported response encoding: %s",
+	"不支持的响应编码: br(未编译 Brotli 支持，请让服务端改用 gzip 或 deflate)": "unsupported response encoding: br (built without Brotli support, have the server use gzip or deflate)",
+	"读取deflate数据失败: %w":                          "failed to read deflate data: %w",
+	"-role-map 格式错误 %q，应为 标准角色=名称":               "invalid -role-map entry %q, expected standard_role=name",
+	"role_map 只能映射 system、user、assistant，不支持 %q": "role_map can only map system, user and assistant, not %q",
+	"-merge-roles 只能为 auto|on|off，不支持 %q":        "-merge-roles must be auto|on|off, not %q",
+	"-stream-usage 只能为 auto|on|off，不支持 %q":       "-stream-usage must be auto|on|off, not %q",
+	"超过 %v 未收到数据":                                "no data received for %v",
+	"读取流失败":                                      "failed to read the stream",
+	"已中断输出":                                      "output interrupted",
+	"无效的 -stop-regex: %w":                        "invalid -stop-regex: %w",
+	"-stop-regex 不能匹配空字符串":                       "-stop-regex must not match the empty string",
+	"\n[服务端不支持流式请求，改用非流式请求] %v\n":                "\n[the server does not support streaming, falling back to a non-streaming request] %v\n",
+	"[DEBUG] 实际发送的用户消息: %q\n":                    "[DEBUG] user message actually sent: %q\n",
+	"\n[DEBUG] 请求体: %s\n":                        "\n[DEBUG] request body: %s\n",
+	"\n[DEBUG] 错误响应体: %s\n":                      "\n[DEBUG] error response body: %s\n",
+	"[VERBOSE] 请求失败: %v\n":                       "[VERBOSE] request failed: %v\n",
+	"[VERBOSE] 响应状态: %s\n":                       "[VERBOSE] response status: %s\n",
+	"\n[DEBUG] 收到事件: %+v\n":                      "\n[DEBUG] received event: %+v\n",
+	"\n[DEBUG] 丢弃重复数据块: %q\n":                    "\n[DEBUG] dropping duplicate chunk: %q\n",
+	"\n[DEBUG] 本次请求耗时: %.2fs\n":                  "\n[DEBUG] request took %.2fs\n",
+	"[DEBUG] 请求ID: %s\n":                         "[DEBUG] request ID: %s\n",
+	"[DEBUG] 当前历史消息数: %d\n":                      "[DEBUG] messages in history: %d\n",
+	"[DEBUG] 最后一条历史消息: %+v\n":                    "[DEBUG] last history message: %+v\n",
+	"\n[DEBUG] 收到响应: %+v\n":                      "\n[DEBUG] received response: %+v\n",
+	"[DEBUG] 裁剪历史消息: %d -> %d\n":                 "[DEBUG] trimming history: %d -> %d\n",
+	"与上一条用户消息相同，仍要发送? [y/N] ":                    "Same as the previous user message, send anyway? [y/N] ",
+	"补充说明(直接回车保留已生成内容): ":                        "Add instructions (press Enter to keep the partial reply): ",
+	"已保留部分回复":                                    "Partial reply kept",
+
+	// 启动
+	"解析配置文件失败: %w":                  "failed to parse the config file: %w",
+	"%s:%d: 格式错误，应为 KEY=VALUE":      "%s:%d: invalid line, expected KEY=VALUE",
+	"加载 .env 失败: %v\n":              "Failed to load .env: %v\n",
+	"错误：加载环境文件失败: %v\n":             "Error: failed to load the env file: %v\n",
+	"错误：环境变量 %s 的值无效: %v\n":         "Error: invalid value for environment variable %s: %v\n",
+	"解析示例文件失败: %w":                  "failed to parse the examples file: %w",
+	"示例 %d 的 role 无效: %q":           "example %d has an invalid role: %q",
+	"示例 %d 的 content 为空":            "example %d has empty content",
+	"错误：加载示例文件失败: %v\n":             "Error: failed to load the examples file: %v\n",
+	"错误：加载工具定义失败: %v\n":             "Error: failed to load the tool definitions: %v\n",
+	"错误：启动指标服务失败: %v\n":             "Error: failed to start the metrics server: %v\n",
+	"错误：启动服务失败: %v\n":               "Error: failed to start the server: %v\n",
+	"错误：-c 和 -f 不能同时使用":             "Error: -c and -f cannot be used together",
+	"错误：读取提问文件失败: %v\n":             "Error: failed to read the prompt file: %v\n",
+	"错误：提问文件 %s 为空\n":               "Error: the prompt file %s is empty\n",
+	"打开 -record 文件失败: %w":           "failed to open the -record file: %w",
+	"警告：写入 -record 文件失败，停止记录: %v\n": "Warning: failed to write the -record file, recording stopped: %v\n",
+	"读取 -replay 文件失败: %w":           "failed to read the -replay file: %w",
+	"解析 -replay 文件失败: %w":           "failed to parse the -replay file: %w",
+
+	// 服务模式
+	"-serve 需要用 -serve-token 设置访问令牌":     "-serve requires an access token set with -serve-token",
+	"%s 不是本机地址，确需对外提供服务请加 -serve-public": "%s is not a loopback address, add -serve-public to serve other hosts",
+	"访问令牌无效": "invalid access token",
+	"已在 %s 提供 POST /v1/chat，默认模型 %s\n": "Serving POST /v1/chat on %s, default model %s\n",
+	"只支持 POST":            "only POST is supported",
+	"解析请求失败: %w":          "failed to parse the request: %w",
+	"messages 不能为空":       "messages must not be empty",
+	"消息 %d 的 role 无效: %q": "message %d has an invalid role: %q",
+	"请求失败: %v\n":          "Request failed: %v\n",
+
+	// 输出目标
+	"打开输出目标失败: %w":                                  "failed to open the output target: %w",
+	"打开 -tee 文件失败: %w":                              "failed to open the -tee file: %w",
+	"\n写入 -tee 文件失败，后续内容不再写入: %v\n":                 "\nFailed to write the -tee file, no further output will be written: %v\n",
+	"不支持的输出目标 %q，可选 ws://...、wss://... 或 fifo:<路径>": "unsupported output target %q, use ws://..., wss://... or fifo:<path>",
+	"生成 WebSocket 密钥失败: %w":                         "failed to generate the WebSocket key: %w",
+	"生成 WebSocket 掩码失败: %w":                         "failed to generate the WebSocket mask: %w",
+	"写入输出目标失败: %v\n":                                "Failed to write to the output target: %v\n",
+	"WebSocket 握手失败: ":                              "WebSocket handshake failed: ",
+
+	// 剪贴板与本地命令
+	"未找到剪贴板工具(需要 wl-paste、xclip 或 xsel)": "no clipboard tool found (wl-paste, xclip or xsel is required)",
+	"读取剪贴板失败: %w":                        "failed to read the clipboard: %w",
+	"剪贴板为空":                              "The clipboard is empty",
+	"错误：剪贴板内容 %d 字节，超出大小限制 %d 字节(-max-context-bytes)\n": "Error: the clipboard holds %d bytes, over the %d byte limit (-max-context-bytes)\n",
+	"已附加剪贴板内容 %d 字节，约 %d tokens(将附加到下一条消息)\n":           "Attached %d bytes from the clipboard, about %d tokens (added to the next message)\n",
+	"用法: !<命令>":        "Usage: !<command>",
+	"错误：命令执行超时(%ds)\n": "Error: the command timed out (%ds)\n",
+	"命令退出: %v\n":       "Command exited: %v\n",
+	"(输出将附加到下一条消息)":    "(the output will be added to the next message)",
+
+	// 导出与回放
+	"系统":                  "System",
+	"用户":                  "User",
+	"对话记录":                "Conversation",
+	"错误：-n 需要正整数":         "Error: -n requires a positive integer",
+	"用法: /replay [-n 轮数]": "Usage: /replay [-n turns]",
+	"暂无对话内容":              "No conversation yet",
+	"用法: /export <md|json|csv|html> <路径>":  "Usage: /export <md|json|csv|html> <path>",
+	"错误：不支持的导出格式 %q，可选 md|json|csv|html\n": "Error: unsupported export format %q, use md|json|csv|html\n",
+	"导出失败: %v\n":       "Export failed: %v\n",
+	"已导出 %d 条消息到 %s\n": "Exported %d messages to %s\n",
+	"未设置":              "not set",
+
+	// 置顶与检查
+	"已置顶消息 %d\n":            "Pinned message %d\n",
+	"已取消置顶消息 %d\n":          "Unpinned message %d\n",
+	"暂无置顶消息":                "No pinned messages",
+	"置顶消息:":                 "Pinned messages:",
+	"对话消息(序号用于 /pin <序号>):": "Messages (use the index with /pin <index>):",
+	"未发现问题":                 "No problems found",
+	"发现 %d 个问题:\n":          "Found %d problem(s):\n",
+	"上下文约 %d tokens，已接近 %s 的上下文长度 %d": "the context is about %d tokens, close to the %s context length of %d",
+	"缺少系统提示词":                 "there is no system prompt",
+	"系统提示词为空":                 "the system prompt is empty",
+	"消息 %d 与消息 %d 重复(%s)":     "message %d duplicates message %d (%s)",
+	"最后一条消息(%d, %s)似乎在句子中间结束": "the last message (%d, %s) seems to end mid-sentence",
+
+	// 统计与费用
+	"[用量未知]": "[usage unknown]",
+	"暂无用量数据": "No usage data yet",
+	"费用估算:":  "Estimated cost:",
+	"模型":     "Model",
+	"输入":     "Input",
+	"输出":     "Output",
+	"费用(元)":  "Cost (CNY)",
+	"未知价格":   "unknown price",
+	"  合计约 %.4f 元(价格为估算值，可在配置文件的 pricing 中修改)\n": "  Total about %.4f CNY (prices are estimates, change them under pricing in the config file)\n",
+	"暂无统计数据":              "No statistics yet",
+	"会话统计(%d 轮):\n":       "Session statistics (%d turns):\n",
+	"指标":                  "Metric",
+	"最小":                  "Min",
+	"平均":                  "Avg",
+	"最大":                  "Max",
+	"延迟(s)":               "Latency (s)",
+	"首字(s)":               "TTFT (s)",
+	"用法: /benchmark <次数>": "Usage: /benchmark <runs>",
+	"错误：次数必须为 1 到 %d 的整数\n":                         "Error: the number of runs must be an integer from 1 to %d\n",
+	"  [%d/%d] 失败: %v\n":                            "  [%d/%d] failed: %v\n",
+	"  [%d/%d] 延迟 %.2fs, 首字 %.2fs, %.1f tokens/s\n": "  [%d/%d] latency %.2fs, TTFT %.2fs, %.1f tokens/s\n",
+	"\n已中断基准测试(完成 %d/%d)\n":                         "\nBenchmark interrupted (%d/%d finished)\n",
+	"全部请求失败，没有统计数据":                                 "All requests failed, no statistics",
+	"基准测试 %s(成功 %d/%d):\n":                          "Benchmark %s (%d/%d succeeded):\n",
+
+	// 总结与标题
+	"用法: /summarize <文件路径>":                   "Usage: /summarize <file>",
+	"错误：%s 是目录\n":                             "Error: %s is a directory\n",
+	"错误：文件超出大小限制 %d 字节(-max-context-bytes)\n": "Error: the file exceeds the %d byte limit (-max-context-bytes)\n",
+	"是否将本次总结加入对话历史? [y/N] ":                   "Add this summary to the conversation history? [y/N] ",
+	"已加入对话历史":                                 "Added to the conversation history",
+	"会话标题: %s\n":                              "Session title: %s\n",
+	"错误：对话为空，无法生成标题":                          "Error: the conversation is empty, cannot generate a title",
+	"生成标题失败: %v\n":                            "Failed to generate a title: %v\n",
+
+	// 命令用法
+	"/model [模型名]":                    "/model [model]",
+	"/model info [模型名]":               "/model info [model]",
+	"/compare <模型名>":                  "/compare <model>",
+	"/pick [关键字]":                     "/pick [keyword]",
+	"/benchmark <次数>":                 "/benchmark <count>",
+	"/title [标题]":                     "/title [title]",
+	"/replay [-n 轮数]":                 "/replay [-n turns]",
+	"/retry-with <参数>=<值>":            "/retry-with <param>=<value>",
+	"/replay-from <序号>":               "/replay-from <index>",
+	"/export <md|json|csv|html> <路径>": "/export <md|json|csv|html> <path>",
+	"/presence [值|off]":               "/presence [value|off]",
+	"/history-search <关键字>":           "/history-search <term>",
+	"/replay-last <轮数>":               "/replay-last <turns>",
+	"/overlay push <提示词>":             "/overlay push <prompt>",
+	"/history-run <序号>":               "/history-run <index>",
+	"/prompt save <名称>":               "/prompt save <name>",
+	"/prompt use <名称>":                "/prompt use <name>",
+	"/prompt-preview [消息]":            "/prompt-preview [message]",
+	"/role <名称>":                      "/role <name>",
+	"/fork [分支名]":                     "/fork [branch]",
+	"/branch switch <分支名>":            "/branch switch <branch>",
+	"/pin [序号]":                       "/pin [index]",
+	"/unpin <序号>":                     "/unpin <index>",
+	"/context add <通配符>":              "/context add <glob>",
+	"/summarize <文件>":                 "/summarize <file>",
+	"/set <名称> <值>":                   "/set <name> <value>",
+	"/unset <名称>":                     "/unset <name>",
+	"/alias <名称> <展开内容>":              "/alias <name> <expansion>",
+	"/wait <秒数>":                      "/wait <seconds>",
+	"@<模型名>: <消息>":                    "@<model>: <message>",
+	"!<命令>":                           "!<command>",
+
+	// 命令说明
+	"显示本帮助":       "Show this help",
+	"显示当前会话的完整信息": "Show full information about the current session",
+	"显示本次会话的延迟、首字时间和生成速度统计":                           "Show latency, time-to-first-token and generation speed for this session",
+	"检查对话中的常见问题(上下文接近上限、重复消息、系统提示词为空、消息未写完)":          "Check the conversation for common issues (near the context limit, duplicate messages, empty system prompt, unfinished message)",
+	"提供工具调用的结果(需 -tools)，全部调用都有结果后自动继续回答":             "Provide the result of a tool call (requires -tools); the reply continues once every call has a result",
+	"查看/切换按用户消息的语言要求回复语言":                             "Show/toggle asking for replies in the language of the user message",
+	"列出本次会话中失败的请求(时间、状态码、错误信息)":                       "List failed requests in this session (time, status code, error message)",
+	"在系统提示词之上叠加一层提示词(发送时作为额外的系统消息)":                   "Layer a prompt on top of the system prompt (sent as an extra system message)",
+	"移除最上层/列出/清除叠加的提示词":                               "Remove the top/list/clear the layered prompts",
+	"在当前系统提示词上开始新对话，按顺序重新发送最后几轮的用户消息(可先用 /model 换模型)": "Start a new conversation on the current system prompt and re-send the last n user messages in order (switch with /model first to use another model)",
+	"按模型估算本次会话的费用(价格可在配置文件的 pricing 中修改)":             "Estimate the session cost per model (prices can be changed under pricing in the config file)",
+	"用固定问题多次请求当前模型并统计速度(不影响对话历史)":                     "Send a fixed question to the current model repeatedly and measure speed (history is unchanged)",
+	"自动生成或手动设置会话标题(用于提示符和会话文件名)":                      "Generate or set the session title (used in the prompt and session file name)",
+	"清除对话历史":     "Clear the conversation history",
+	"重新生成最后一条回复": "Regenerate the last reply",
+	"临时使用指定参数(temp, top_p, presence, seed, model)重新生成最后一条回复": "Regenerate the last reply with temporary settings (temp, top_p, presence, seed, model)",
+	"逐行比较最近两次回复的差异":                                          "Show a line diff of the last two replies",
+	"重新显示对话内容":                                               "Redisplay the conversation",
+	"导出对话(CSV 列: index, role, content, char_count, estimated_tokens, timestamp；html 为带样式的独立网页)": "Export the conversation (CSV columns: index, role, content, char_count, estimated_tokens, timestamp; html is a standalone styled page)",
+	"丢弃该消息之后的对话，最后为用户消息时重新生成回复(不带序号时列出消息)":                                                      "Discard everything after that message and regenerate if it is a user turn (lists messages without an index)",
+	"显示/切换模型":                            "Show or switch the model",
+	"查看模型能力(上下文长度、多模态等)":                 "Show model capabilities (context length, multimodal, etc.)",
+	"从列表中选择模型(可输入序号或名称片段模糊筛选)":           "Pick a model from a list (enter a number or part of a name to filter)",
+	"用另一个模型回答上一个问题并对比(不影响对话历史)":          "Answer the last question with another model and compare (history is unchanged)",
+	"切换调试信息":                             "Toggle debug output",
+	"切换深度思考(enable_thinking)并显示思考过程":     "Toggle deep thinking (enable_thinking) and show the reasoning",
+	"查看/设置存在惩罚(-2 到 2)":                  "Show or set the presence penalty (-2 to 2)",
+	"查看/切换回复格式(json 时请求 json_object 格式)": "Show or switch the reply format (json requests json_object)",
+	"查看命令历史":                             "Show the command history",
+	"清空命令历史":                             "Clear the command history",
+	"在命令历史(包括以往会话)中查找":                   "Search the command history, including past sessions",
+	"重新执行命令历史中的一条":                       "Re-run an entry from the command history",
+	"保存当前系统提示词为模板":                       "Save the current system prompt as a template",
+	"使用模板作为系统提示词":                        "Use a template as the system prompt",
+	"列出提示词模板":                            "List prompt templates",
+	"显示实际将发送的 messages(含上下文文件、变量替换和前后缀)，不发送请求": "Show the exact messages that would be sent (context files, variables, prefix/suffix applied) without sending",
+	"使用角色文件($HOME/.abls/roles/<名称>.md)作为系统提示词": "Use a role file ($HOME/.abls/roles/<name>.md) as the system prompt",
+	"列出可用角色":          "List available roles",
+	"复制当前对话为新分支并切换过去": "Copy the conversation into a new branch and switch to it",
+	"列出对话分支":          "List conversation branches",
+	"切换到指定分支":         "Switch to the given branch",
+	"置顶消息使其不被裁剪(不带序号时列出全部消息)": "Pin a message so it is never trimmed (lists all messages without an index)",
+	"取消置顶":   "Unpin a message",
+	"查看置顶消息": "Show pinned messages",
+	"加载文件作为上下文(不会被裁剪)":                          "Load files as context (never trimmed)",
+	"查看/清除上下文文件":                                "List or clear context files",
+	"总结文件内容(单独请求，确认后才加入对话历史)":                   "Summarise a file (separate request, added to history only after confirmation)",
+	"定义变量，发送消息时替换其中的 {{名称}}":                    "Define a variable; {{name}} in messages is replaced when sending",
+	"把剪贴板内容附加到下一条消息(大小受 -max-context-bytes 限制)": "Attach the clipboard contents to the next message (limited by -max-context-bytes)",
+	"删除变量": "Delete a variable",
+	"列出变量": "List variables",
+	"定义别名(展开为命令时执行，否则作为消息发送)":            "Define an alias (run if it expands to a command, otherwise sent as a message)",
+	"列出别名(可在配置文件的 aliases 中预先定义)":        "List aliases (can be predefined under aliases in the config file)",
+	"暂停指定时间(用于脚本驱动交互会话)":                 "Pause for the given time (for scripted interactive sessions)",
+	"只用指定模型(或模型别名)回答这一条消息，之后恢复当前模型":      "Answer just this message with the given model (or model alias), then switch back",
+	"执行本地命令并把输出附加到下一条消息(需 -allow-shell)": "Run a local command and attach its output to the next message (requires -allow-shell)",
+	"退出程序": "Exit the program",
+}
+
+const enBanner = `
+Alibaba Cloud Bailian chat client
+----------------------------------
+Current settings:
+  Model: {{model}}
+  Debug mode: {{debug}}
+  History file: {{history}}
+----------------------------------
+Commands:
+  /help        Show help
+  /info        Show session information
+  /reset       Reset the conversation
+  /model <model> Switch model (Ctrl+N cycles)
+  /debug       Toggle debug mode
+  /think       Toggle deep thinking
+  /history     Show command history
+  /prompt      Manage prompt templates
+  exit         Exit the program
+----------------------------------
+`
+
+const enHelpFooter = `
+Press Ctrl+C while a reply is streaming to interrupt it (the program keeps running), then you are asked for a follow-up:
+  Type text and press Enter: the partial reply and your note are used as context to regenerate; you can interrupt again
+  Press Enter alone: keep the partial reply and return to normal input
+
+Single-command options:
+  -c string    Run one command and exit
+  -f path      Read the file as the single command (content is sent verbatim)
+  --stream     Stream the output in single-command mode
+  -continue    Restore the most recently saved conversation in interactive mode
+  -json        Output JSON in single-command mode (includes partial content on error)
+  -quiet       Print only the model reply, suitable as a pipe filter
+  -banner off  Do not show the welcome message (or customise it with -banner custom -banner-file)
+  -count n     Request n answers per question and print them numbered
+  -prefix/-suffix  Add a prefix/suffix to every user message
+  -seed n      Fix the random seed for reproducible output (provider must support seed)
+  -temperature/-top-p  Fix sampling parameters regardless of model (can be set per model under model_profiles in the config file)
+  -lang en|zh  Interface language (detected from LANG by default)
+
+Examples:
+  # Single command
+  ./abls -c "hello"
+
+  # Single command, streamed
+  ./abls -c "hello" --stream
+
+  # Interactive mode
+  ./abls
+
+  # Send an initial message, then keep chatting
+  ./abls -i "$(cat setup.txt)"
+
+  # Save the API key to the OS keychain, then read it from there
+  ./abls login
+  ./abls -keychain`
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"unicode"
+)
+
+func (u *Usage) add(other Usage) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalTokens += other.TotalTokens
+}
+
+// 粗略估算 token 数：中日韩字符约一字一个，其余约四个字符一个
+func estimateTokens(text string) int {
+	wide, other := 0, 0
+	for _, r := range text {
+		switch {
+		case runeWidth(r) == 2:
+			wide++
+		case !unicode.IsSpace(r):
+			other++
+		}
+	}
+	return wide + (other+3)/4
+}
+
+func estimateMessagesTokens(messages []Message) int {
+	total := 0
+	for _, msg := range messages {
+		total += estimateTokens(msg.Content) + 4
+	}
+	return total
+}
+
+func printSessionInfo(state *ChatState) {
+	fmt.Printf(`会话信息:
+  模型: %s
+  API地址: %s
+  超时: %ds
+  深度思考: %s
+  随机种子: %s
+  存在惩罚: %s
+  温度: %s
+  Top-P: %s
+  回答数量: %d
+  调试模式: %v
+  消息数: %d
+  当前上下文估算: ~%d tokens
+  累计用量: 输入 %d / 输出 %d / 总计 %d tokens
+  最后请求ID: %s
+`, state.Model, *apiEndpoint, *timeoutSec, optionalText(state.EnableThinking),
+		optionalText(state.Seed), optionalText(state.PresencePenalty),
+		optionalText(state.Temperature), optionalText(state.TopP),
+		max(*completionCount, 1), state.Debug,
+		len(state.History), estimateMessagesTokens(buildMessages(state)),
+		state.TotalUsage.PromptTokens, state.TotalUsage.CompletionTokens, state.TotalUsage.TotalTokens,
+		state.LastRequestID)
+}
+
+// 可选请求参数的展示文本，nil 表示未设置
+func optionalText[T any](v *T) string {
+	if v == nil {
+		return msg("未设置")
+	}
+	return fmt.Sprint(*v)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// -json 模式下的输出结构
+type jsonReply struct {
+	Model     string `json:"model"`
+	RequestID string `json:"request_id,omitempty"`
+	Content   string `json:"content"`
+	Usage     *Usage `json:"usage,omitempty"`
+
+	LogProbs []TokenLogProb `json:"logprobs,omitempty"`
+}
+
+type jsonError struct {
+	Error   string `json:"error"`
+	Status  int    `json:"status,omitempty"`
+	Partial string `json:"partial,omitempty"`
+}
+
+func printJSONResult(state *ChatState, err error) {
+	var out any
+	result := state.LastResult
+	if err != nil {
+		errOut := jsonError{Error: err.Error()}
+		if result != nil {
+			errOut.Status = result.StatusCode
+			errOut.Partial = result.Content
+		}
+		out = errOut
+	} else {
+		reply := jsonReply{Model: state.Model, Content: state.History[len(state.History)-1].Content}
+		if result != nil {
+			reply.RequestID = result.RequestID
+			reply.Usage = result.Usage
+			reply.LogProbs = result.LogProbs
+		}
+		out = reply
+	}
+
+	data, marshalErr := json.Marshal(out)
+	if marshalErr != nil {
+		fmt.Fprintf(os.Stderr, msg("JSON编码失败: %v\n"), marshalErr)
+		return
+	}
+	fmt.Println(string(data))
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"readline"
+)
+
+// 系统密钥库中的服务名，账户名为 API 地址的主机名，不同服务商的密钥分开保存
+const keychainService = "abls"
+
+var errKeychainUnavailable error = keychainUnavailableError{}
+
+// 包初始化时界面语言尚未确定，错误信息在输出时再翻译
+type keychainUnavailableError struct{}
+
+func (keychainUnavailableError) Error() string { return msg("系统密钥库不可用") }
+
+func keychainAccount() string {
+	if u, err := url.Parse(*apiEndpoint); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return *apiEndpoint
+}
+
+// Windows 凭据管理器没有读取密码的命令行工具，通过 PowerShell 调用 PasswordVault
+const (
+	vaultLoad     = "[void][Windows.Security.Credentials.PasswordVault,Windows.Security.Credentials,ContentType=WindowsRuntime]; $v = New-Object Windows.Security.Credentials.PasswordVault; "
+	vaultStore    = vaultLoad + "$v.Add((New-Object Windows.Security.Credentials.PasswordCredential(%s, %s, [Console]::In.ReadLine())))"
+	vaultRetrieve = vaultLoad + "$c = $v.Retrieve(%s, %s); $c.RetrievePassword(); $c.Password"
+)
+
+func powershellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// security -i 从标准输入读取命令，参数中的引号和反斜杠需要转义
+func securityQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// 密钥一律通过标准输入传给工具，不出现在进程参数中(ps 或 /proc/*/cmdline 可见)
+func keychainStore(account, secret string) error {
+	var (
+		cmd   *exec.Cmd
+		input = secret + "\n"
+	)
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "-i")
+		input = fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n",
+			securityQuote(keychainService), securityQuote(account), securityQuote(secret))
+	case "windows":
+		cmd = exec.Command("powershell", "-NoProfile", "-Command",
+			fmt.Sprintf(vaultStore, powershellQuote(keychainService), powershellQuote(account)))
+	default:
+		cmd = exec.Command("secret-tool", "store", "--label", "abls API密钥 ("+account+")", "service", keychainService, "account", account)
+	}
+	errOutput, err := runKeychainToolWithInput(cmd, input)
+	errOutput = strings.TrimSpace(errOutput)
+	// security -i 中单条命令失败时只输出错误信息，退出码仍为 0
+	if err == nil && runtime.GOOS == "darwin" && errOutput != "" {
+		err = errors.New(msg("security 执行失败"))
+	}
+	if err != nil && errOutput != "" {
+		return fmt.Errorf("%w: %s", err, errOutput)
+	}
+	return err
+}
+
+func keychainLookup(account string) (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", keychainService, "-a", account, "-w")
+	case "windows":
+		cmd = exec.Command("powershell", "-NoProfile", "-Command",
+			fmt.Sprintf(vaultRetrieve, powershellQuote(keychainService), powershellQuote(account)))
+	default:
+		cmd = exec.Command("secret-tool", "lookup", "service", keychainService, "account", account)
+	}
+	output, err := runKeychainTool(cmd)
+	if errors.Is(err, errKeychainUnavailable) {
+		return "", err
+	}
+	secret := strings.TrimSpace(output)
+	if err != nil || secret == "" {
+		return "", fmt.Errorf(msg("系统密钥库中没有 %s 的API密钥，请先运行 abls login"), account)
+	}
+	return secret, nil
+}
+
+// 通过管道写入标准输入并检查写入是否成功，返回工具的错误输出
+func runKeychainToolWithInput(cmd *exec.Cmd, input string) (string, error) {
+	if cmd.Err != nil {
+		return runKeychainTool(cmd)
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return "", err
+	}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+
+	_, writeErr := io.WriteString(stdin, input)
+	if closeErr := stdin.Close(); writeErr == nil {
+		writeErr = closeErr
+	}
+	if err := cmd.Wait(); err != nil {
+		return stderr.String(), err
+	}
+	if writeErr != nil {
+		return stderr.String(), fmt.Errorf(msg("写入密钥失败: %w"), writeErr)
+	}
+	return stderr.String(), nil
+}
+
+func runKeychainTool(cmd *exec.Cmd) (string, error) {
+	if cmd.Err != nil {
+		return "", fmt.Errorf(msg("%w: 未找到 %s(macOS 使用 security，Linux 需要 secret-tool，Windows 需要 PowerShell)"), errKeychainUnavailable, cmd.Args[0])
+	}
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return string(exitErr.Stderr), err
+		}
+		return "", err
+	}
+	return string(output), nil
+}
+
+// abls login: 读取API密钥并保存到系统密钥库，之后加 -keychain 运行即可读取
+func runLogin() {
+	account := keychainAccount()
+	secret, err := readSecret(fmt.Sprintf(msg("%s 的API密钥: "), account))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, msg("错误：读取API密钥失败: %v\n"), err)
+		os.Exit(1)
+	}
+	if secret == "" {
+		fmt.Fprintln(os.Stderr, msg("错误：API密钥为空"))
+		os.Exit(1)
+	}
+	if err := keychainStore(account, secret); err != nil {
+		fmt.Fprintf(os.Stderr, msg("错误：保存到系统密钥库失败: %v\n"), err)
+		os.Exit(1)
+	}
+	fmt.Printf(msg("已将 %s 的API密钥保存到系统密钥库，运行时加 -keychain 读取\n"), account)
+}
+
+// 终端中输入时不回显，也可以通过管道传入
+func readSecret(prompt string) (string, error) {
+	if !readline.IsTerminal(int(os.Stdin.Fd())) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+		return strings.TrimSpace(line), nil
+	}
+
+	rl, err := readline.NewEx(&readline.Config{})
+	if err != nil {
+		return "", err
+	}
+	defer rl.Close()
+	secret, err := rl.ReadPassword(prompt)
+	return strings.TrimSpace(string(secret)), err
+}
+
+// -keychain: 未通过参数或环境变量提供密钥时从系统密钥库读取，读取失败只警告，
+// 随后按未提供密钥处理
+func loadKeychainKey() {
+	if *apiKey != "" || !*useKeychain {
+		return
+	}
+	secret, err := keychainLookup(keychainAccount())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, msg("警告：从系统密钥库读取API密钥失败: %v\n"), err)
+		return
+	}
+	*apiKey = secret
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// 上下文占用超过模型上下文长度的该比例时给出警告
+const lintContextRatio = 0.8
+
+// /lint: 用简单规则检查对话历史中的常见问题
+func lintHistory(state *ChatState) {
+	warnings := lintWarnings(state)
+	if len(warnings) == 0 {
+		fmt.Println(msg("未发现问题"))
+		return
+	}
+
+	fmt.Printf(msg("发现 %d 个问题:\n"), len(warnings))
+	for _, w := range warnings {
+		fmt.Println(colorize(ansiYellow, "  ! "+w))
+	}
+}
+
+func lintWarnings(state *ChatState) []string {
+	var warnings []string
+
+	tokens := estimateMessagesTokens(buildMessages(state))
+	if info, ok := lookupModel(state.Model); ok && float64(tokens) >= float64(info.ContextLength)*lintContextRatio {
+		warnings = append(warnings, fmt.Sprintf(msg("上下文约 %d tokens，已接近 %s 的上下文长度 %d"), tokens, state.Model, info.ContextLength))
+	}
+
+	if len(state.History) == 0 || state.History[0].Role != "system" {
+		warnings = append(warnings, msg("缺少系统提示词"))
+	} else if strings.TrimSpace(state.History[0].Content) == "" {
+		warnings = append(warnings, msg("系统提示词为空"))
+	}
+
+	seen := map[string]int{}
+	for i, m := range state.History {
+		if m.Role == "system" {
+			continue
+		}
+		key := m.Role + "\x00" + strings.TrimSpace(m.Content)
+		if first, ok := seen[key]; ok {
+			warnings = append(warnings, fmt.Sprintf(msg("消息 %d 与消息 %d 重复(%s)"), i, first, m.Role))
+			continue
+		}
+		seen[key] = i
+	}
+
+	if last := len(state.History) - 1; last > 0 && endsMidSentence(state.History[last].Content) {
+		warnings = append(warnings, fmt.Sprintf(msg("最后一条消息(%d, %s)似乎在句子中间结束"), last, state.History[last].Role))
+	}
+	return warnings
+}
+
+// 结尾不是句末标点、右括号或代码块结束标记时视为句子未结束
+func endsMidSentence(content string) bool {
+	content = strings.TrimSpace(content)
+	if content == "" || strings.HasSuffix(content, "```") {
+		return false
+	}
+	last, _ := utf8.DecodeLastRuneInString(content)
+	return !strings.ContainsRune(".!?。！？…:：;；)）]】\"'”’」』>*`", last)
+}
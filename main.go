@@ -1,16 +1,23 @@
 package main
 
 import (
-	"bufio"
 	"bytes"
+	"context"
+	"crypto/rand"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"os/user"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 	"readline"
 	//"github.com/chzyer/readline"
@@ -18,131 +25,764 @@ import (
 
 // 配置参数
 var (
-	apiKey       = flag.String("key", os.Getenv("ABL_API_KEY"), "API密钥(可使用变量ABL_API_KEY)")
-	defaultModel = flag.String("model", "qwen-plus", "默认模型名称")
+	apiKey       = flag.String("key", "", "API密钥(可使用变量ABL_API_KEY)，所有参数均可用 ABLS_<参数名> 环境变量设置")
+	defaultModel = flag.String("model", "qwen-plus", "默认模型名称(可使用配置文件 model_aliases 中的别名)")
 	apiEndpoint  = flag.String("api", "https://dashscope.aliyuncs.com/compatible-mode/v1/chat/completions", "百炼API")
 	timeoutSec   = flag.Int("timeout", 300, "请求超时时间（秒）")
 	historyFile  = flag.String("history", "", "历史记录文件路径")
 	command      = flag.String("c", "", "直接执行单条命令后退出")
+	promptFile   = flag.String("f", "", "读取文件全部内容作为单条命令执行后退出(不能与 -c 同时使用)")
 	enableStream = flag.Bool("stream", false, "在 -c 模式下启用流式输出")
 	enableDebug  = flag.Bool("debug", false, "初始调试模式状态")
+	verbose      = flag.Bool("verbose", false, "输出HTTP请求方法、地址、响应状态及关键响应头到标准错误")
+
+	cmdHistoryFile = flag.String("cmd-history", "", "命令历史持久化文件路径(默认 $HOME/.abls/cmd_history.json)")
+
+	completionCount = flag.Int("count", 1, "每次提问请求的回答数量(n>1时逐条编号输出)")
+
+	checkEndpoint = flag.Bool("check-endpoint", false, "启动时探测API地址是否可达")
+
+	enableThinking = flag.Bool("thinking", false, "设置 enable_thinking 参数(未指定时不发送)")
+	showReasoning  = flag.Bool("show-reasoning", false, "显示模型的思考过程(reasoning_content)")
+
+	authHeader = flag.String("auth-header", "Authorization", "携带API密钥的请求头名称(-format anthropic 时默认用 x-api-key 且不加前缀)")
+	authPrefix = flag.String("auth-prefix", "Bearer ", "API密钥前缀(可设为空)")
+
+	wrapOutput = flag.Bool("wrap", true, "非流式输出时按终端宽度自动换行(输出到管道时不生效)")
+
+	jsonResponse = flag.Bool("json-response", false, "请求JSON格式的回复(response_format 为 json_object)，回复不是合法JSON时警告")
+
+	prettyJSON = flag.Bool("pretty-json", false, "非流式输出时，整段回复或 ```json 代码块为合法JSON则重新缩进")
+
+	// 在 flag.Parse 之前由 loadEnvironment 预扫描，这里只负责注册参数
+	_ = flag.String("env-file", "", "环境变量文件路径(默认读取当前目录的 .env)")
+
+	dedupChunks = flag.Bool("dedup", false, "丢弃与上一个数据块完全相同的内容块(用于会重复推送的网关)")
+
+	requestFormat = flag.String("format", "openai", "请求体格式: openai|anthropic")
+
+	resumeAttempts = flag.Int("resume", 0, "流式连接中途断开时携带已收到内容重试的次数(0为关闭)")
+
+	maxMessages = flag.Int("max-messages", 0, "对话历史最多保留的消息数，超出时裁剪最早的未置顶消息(0为不限)")
+
+	messagePrefix = flag.String("prefix", "", "添加到每条用户消息开头的文本")
+	messageSuffix = flag.String("suffix", "", "添加到每条用户消息末尾的文本")
+
+	seed = flag.Int("seed", 0, "随机种子，用于获得可复现的输出(未指定时不发送)")
+
+	allowShell   = flag.Bool("allow-shell", false, "允许使用 !命令 执行本地命令并将输出附加到下一条消息")
+	shellTimeout = flag.Int("shell-timeout", 30, "!命令 的执行超时时间（秒）")
+
+	presencePenalty = flag.Float64("presence-penalty", 0, "存在惩罚，范围 -2 到 2(未指定时不发送)")
+
+	continueSession = flag.Bool("continue", false, "启动时恢复最近一次保存的对话")
+	keepSessions    = flag.Int("keep-sessions", 20, "退出时自动保存的会话最多保留的个数，超出时删除最早的(0为不限)")
+
+	jsonOutput = flag.Bool("json", false, "在 -c 模式下以JSON格式输出结果(出错时包含已收到的部分内容)")
+
+	maxIdleConns     = flag.Int("max-idle-conns", 10, "连接池最大空闲连接数")
+	idleTimeoutSec   = flag.Int("idle-timeout", 30, "空闲连接保持时间（秒）")
+	disableKeepAlive = flag.Bool("disable-keepalive", false, "禁用连接复用，每次请求建立新连接")
+
+	maxContextBytes = flag.Int("max-context-bytes", 256*1024, "/context add 加载文件的总大小上限（字节）")
+
+	quiet = flag.Bool("quiet", false, "只输出模型回复，不显示欢迎信息、调试和状态信息(错误仍输出到标准错误)")
+
+	initialPrompt = flag.String("i", "", "发送一条初始消息后继续进入交互模式")
+
+	flushOutput = flag.Bool("flush", false, "流式输出时每个数据块后立即刷新标准输出")
+
+	noKeyCheck = flag.Bool("no-key-check", false, "跳过API密钥格式检查")
+
+	idleStreamTimeout = flag.Int("idle-stream-timeout", 0, "流式响应超过该秒数未收到数据时中止(0为关闭)，开启后 -timeout 只限制等待响应头的时间")
+
+	warnUnsetVars = flag.Bool("warn-unset-vars", false, "消息中有未定义的 {{变量}} 时发出警告(占位符总是原样保留)")
+
+	idempotencyKey = flag.Bool("idempotency-key", false, "每轮请求生成 Idempotency-Key 请求头，同一轮的重试(续传、非流式回退)沿用同一个值")
+
+	startupRole = flag.String("role", "", "启动时使用的角色($HOME/.abls/roles/<名称>.md)")
+
+	bannerMode = flag.String("banner", "default", "启动欢迎信息: off|default|custom(custom 时使用 -banner-file 模板)")
+	bannerFile = flag.String("banner-file", "", "自定义欢迎信息模板文件，可使用 {{model}} {{debug}} {{history}} 变量")
+
+	messageTimestamps = flag.Bool("timestamps", false, "记录每条消息的时间，保存会话和导出时包含")
+
+	useKeychain = flag.Bool("keychain", false, "未提供 -key 和 ABL_API_KEY 时从系统密钥库读取API密钥(先运行 abls login 保存)")
+
+	noColor = flag.Bool("no-color", false, "不输出任何颜色(也可设置 NO_COLOR 环境变量)")
+
+	autoLang = flag.Bool("auto-lang", false, "按用户消息的文字判断语言(日语、韩语、中文等)，要求模型用该语言回复(仅附加在发送的请求中，可用 /autolang 切换)")
+
+	serveAddr   = flag.String("serve", "", "作为本地网关在该地址(如 127.0.0.1:8080)提供 POST /v1/chat，请求体 {messages, model, stream}")
+	serveToken  = flag.String("serve-token", "", "-serve 要求的访问令牌，请求需带 Authorization: Bearer <令牌>(必填，可用 ABLS_SERVE_TOKEN 设置)")
+	servePublic = flag.Bool("serve-public", false, "允许 -serve 监听非本机地址(默认只接受 127.0.0.1、::1 或 localhost)")
+
+	trimReplies = flag.Bool("trim-reply", true, "保存和输出前去掉回复首尾的空白(流式实时输出不受影响，保留缩进代码块的缩进)")
+
+	toolsFile = flag.String("tools", "", "工具定义文件(JSON 数组，OpenAI tools 格式)，模型发起的调用会显示出来，结果用 /tool-result 提供")
+
+	userID = flag.String("user", "", "请求中的 user 字段，供多用户网关区分请求来源(默认为系统用户名，设为空则不发送)")
+
+	mergeRolesMode = flag.String("merge-roles", "auto", "发送前合并相邻的同角色消息(内容以换行连接): auto|on|off，auto 时只对 anthropic 格式合并")
+
+	typeDelay = flag.Int("type-delay", 0, "流式输出到终端时每个字符间隔的毫秒数，产生打字机效果(用于录屏演示，0为关闭，输出被重定向时忽略)")
+
+	recordFile   = flag.String("record", "", "把流式响应的原始数据及时间间隔记录到该文件(每次请求覆盖)，供 -replay 使用")
+	replayFile   = flag.String("replay", "", "不发送请求，改为回放 -record 记录或原始 SSE 文件(用于演示和测试)")
+	replayTiming = flag.Bool("replay-timing", false, "回放时按记录中的时间间隔输出")
+
+	maxRequestBytes = flag.Int("max-request-bytes", 0, "请求体超过该字节数时不发送并提示精简上下文(0为不限)")
+
+	warnDuplicate = flag.Bool("warn-duplicate", false, "交互模式下发送与上一条用户消息相同的内容前要求确认")
+
+	highlightCode = flag.Bool("highlight", false, "流式输出到终端时为 ``` 代码块着色")
+
+	roleMapFlag = flag.String("role-map", "", "发送时替换角色名，如 system=developer(多个用逗号分隔，可在配置文件 role_map 中设置)")
+
+	metricsAddr = flag.String("metrics-addr", "", "在该地址(如 :9090)提供 /metrics，以 Prometheus 文本格式输出请求数、用量、耗时和错误数")
+
+	examplesFile = flag.String("examples", "", "少样本示例文件(JSON 数组，元素为 {role, content})，插入在系统提示词之后、对话之前")
+
+	pageOutput = flag.Bool("page", false, "非流式输出到终端时用 $PAGER(默认 less)分页显示回复")
+
+	connectTimeout = flag.Int("connect-timeout", 0, "建立连接和收到响应头的超时时间（秒），0为只使用 -timeout(非流式请求需等待完整回复后才返回响应头，应设置得足够长)")
+
+	stopRegex = flag.String("stop-regex", "", "流式回复累计内容匹配该正则时在本地停止并截断到匹配处(用于忽略 stop 参数的服务商)")
+
+	showUsage = flag.Bool("show-usage", false, "每轮回复后显示本轮用量 [↑输入 ↓输出 Σ合计](-quiet 和 -json 时不显示)")
+
+	signSecret          = flag.String("sign-secret", "", "设置后对 \"<时间戳>\\n<请求体>\" 计算 HMAC-SHA256 签名并添加到请求头(用于需要签名的网关)")
+	signHeader          = flag.String("sign-header", "X-Signature", "携带请求签名的请求头名称")
+	signTimestampHeader = flag.String("sign-timestamp-header", "X-Timestamp", "签名请求携带的时间戳(Unix 秒)请求头名称，供网关防止重放")
+
+	langFlag = flag.String("lang", "", "界面语言: zh|en(默认根据 LC_ALL/LC_MESSAGES/LANG 环境变量判断)")
+
+	listCommands = flag.Bool("list-commands", false, "以JSON输出全部交互命令和命令行参数后退出(用于生成 shell 补全)")
+
+	teeFile = flag.String("tee", "", "流式输出回复的同时追加写入该文件")
+
+	sinkTarget = flag.String("sink", "", "流式回复内容改为输出到 ws://地址 或 fifo:<命名管道路径>(WebSocket 模式下每个内容块为一帧JSON)")
+
+	stripThink = flag.Bool("strip-think", false, "从回复中移除 <think>...</think> 包裹的思考过程(开启 -show-reasoning 时以暗色显示)")
+
+	allowNonStreamFallback = flag.Bool("allow-nonstream-fallback", true, "服务端以400拒绝流式请求时自动改用非流式请求")
+
+	streamUsageMode = flag.String("stream-usage", "auto", "流式请求是否附带 stream_options.include_usage 以获取用量: auto|on|off，auto 时只对已知支持的服务商发送")
+
+	configFile  = flag.String("config", "", "配置文件路径(默认 $HOME/.abls/config.json)")
+	temperature = flag.Float64("temperature", 0, "采样温度，范围 0 到 2(未指定时使用配置文件 model_profiles 中该模型的设置)")
+	topP        = flag.Float64("top-p", 0, "核采样概率，大于 0 且不超过 1(未指定时使用配置文件 model_profiles 中该模型的设置)")
+
+	logProbs    = flag.Bool("logprobs", false, "请求返回每个输出token的对数概率(配合 -json 输出)")
+	topLogProbs = flag.Int("top-logprobs", 0, "每个位置返回概率最高的候选token数，范围 0 到 20(隐含 -logprobs)")
 )
 
 // 数据结构
 type Message struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
+
+	Timestamp *time.Time `json:"timestamp,omitempty"` // 仅在 -timestamps 时记录，不发送给API
+
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // 助手发起的工具调用，见 -tools
+	ToolCallID string     `json:"tool_call_id,omitempty"` // tool 角色消息对应的调用
+}
+
+// 对话中新增的消息，-timestamps 时记录当前时间
+func newMessage(role, content string) Message {
+	m := Message{Role: role, Content: content}
+	if *messageTimestamps {
+		now := time.Now()
+		m.Timestamp = &now
+	}
+	return m
+}
+
+// 发送前去掉时间戳，避免服务商收到未知字段
+func withoutTimestamps(messages []Message) []Message {
+	out := make([]Message, len(messages))
+	for i, m := range messages {
+		out[i] = Message{Role: m.Role, Content: m.Content, ToolCalls: m.ToolCalls, ToolCallID: m.ToolCallID}
+	}
+	return out
 }
 
 type StreamRequest struct {
 	Model    string    `json:"model"`
 	Messages []Message `json:"messages"`
 	Stream   bool      `json:"stream"`
+	N        *int      `json:"n,omitempty"`
+
+	EnableThinking *bool `json:"enable_thinking,omitempty"`
+
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+	Seed          *int           `json:"seed,omitempty"`
+
+	PresencePenalty *float64 `json:"presence_penalty,omitempty"`
+	Temperature     *float64 `json:"temperature,omitempty"`
+	TopP            *float64 `json:"top_p,omitempty"`
+
+	LogProbs    *bool `json:"logprobs,omitempty"`
+	TopLogProbs *int  `json:"top_logprobs,omitempty"`
+
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+
+	User string `json:"user,omitempty"` // 供网关区分用户，见 -user
+
+	Tools []json.RawMessage `json:"tools,omitempty"` // -tools 加载的工具定义
+}
+
+type ResponseFormat struct {
+	Type string `json:"type"` // json_object
+}
+
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// 单个输出token的对数概率，TopLogProbs 为同一位置的候选token
+type TokenLogProb struct {
+	Token       string         `json:"token"`
+	LogProb     float64        `json:"logprob"`
+	Bytes       []int          `json:"bytes,omitempty"`
+	TopLogProbs []TokenLogProb `json:"top_logprobs,omitempty"`
+}
+
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens,omitempty"`
+	CompletionTokens int `json:"completion_tokens,omitempty"`
+	TotalTokens      int `json:"total_tokens,omitempty"`
 }
 
 type StreamResponse struct {
 	ID      string `json:"id"`
 	Model   string `json:"model"`
 	Choices []struct {
+		Index int `json:"index"`
 		Delta struct {
-			Content string `json:"content,omitempty"`
+			Content          string          `json:"content,omitempty"`
+			ReasoningContent string          `json:"reasoning_content,omitempty"`
+			ToolCalls        []ToolCallDelta `json:"tool_calls,omitempty"`
 		} `json:"delta"`
 		FinishReason string `json:"finish_reason,omitempty"`
+		LogProbs     *struct {
+			Content []TokenLogProb `json:"content"`
+		} `json:"logprobs,omitempty"`
 	} `json:"choices"`
-	Usage struct {
-		PromptTokens    int `json:"prompt_tokens,omitempty"`
-		CompletionTokens int `json:"completion_tokens,omitempty"`
-		TotalTokens     int `json:"total_tokens,omitempty"`
-	} `json:"usage,omitempty"`
+	Usage *Usage `json:"usage,omitempty"`
+}
+
+// 一次流式请求的解析结果
+type StreamResult struct {
+	Content   string   // 第一个回答(index 0)
+	Reasoning string   // 第一个回答的思考过程
+	Choices   []string // 按 index 排列的全部回答
+	RequestID string
+	Usage     *Usage // 服务端返回的本轮用量，可能为空
+	Done      bool   // 是否收到 [DONE] 或结束原因
+	TTFT      time.Duration
+
+	StatusCode int
+	LogProbs   []TokenLogProb // 第一个回答的对数概率，仅 -logprobs 时有值
+	ToolCalls  []ToolCall     // 第一个回答中的工具调用
+
+	firstTokenAt time.Time
 }
 
+// 流在结束前中断，可用于判断是否续传
+var errStreamBroken error = streamBrokenError{}
+
+// 包初始化时界面语言尚未确定，错误信息在输出时再翻译
+type streamBrokenError struct{}
+
+func (streamBrokenError) Error() string { return msg("读取流失败") }
+
 // 对话状态
 type ChatState struct {
 	Model         string
+	SystemPrompt  string
 	History       []Message
-	CmdHistory    []string
+	CmdHistory    []CmdEntry
 	Client        *http.Client
 	Debug         bool
 	LastRequestID string
 	isSingleCmd   bool
+	rl            *readline.Instance
+
+	streamUnsupported bool   // 服务端拒绝流式请求后改用非流式请求
+	idempotencyKey    string // 当前请求的幂等键，-idempotency-key 时每轮重新生成
+
+	serveOutput func(string) // -serve 模式下流式内容写入 HTTP 响应
+
+	streamMu     sync.Mutex
+	cancelStream context.CancelCauseFunc // 进行中的请求，Ctrl+C 时取消
+	historyMu    sync.Mutex              // 处理输入期间由主循环持有，信号处理读取 History 前需获取
+
+	EnableThinking *bool        // nil 表示不发送 enable_thinking
+	TotalUsage     Usage        // 本次会话累计用量
+	Pinned         map[int]bool // 置顶消息在 History 中的序号
+	Seed           *int         // nil 表示不发送 seed
+	Pending        []string     // 待附加到下一条用户消息的内容
+	Stats          []TurnStat
+	PrevReply      string        // /retry 前的回复，供 /diff 比较
+	LastResult     *StreamResult // 最近一次请求的结果，出错时可能只含部分内容
+	Title          string
+	Role           string       // 通过 /role 或 -role 使用的角色名
+	ContextDocs    []ContextDoc // 不参与裁剪，仅 /context clear 清除
+	Overlays       []string     // /overlay 叠加在系统提示词之上的提示词，由下到上
+
+	PresencePenalty *float64 // nil 表示不发送 presence_penalty
+	Temperature     *float64 // 随模型切换更新，-temperature 指定时固定
+	TopP            *float64
+
+	Aliases map[string]string // 配置文件中的别名及 /alias 定义的别名
+	Vars    map[string]string // /set 定义的变量，发送前替换消息中的 {{名称}}
+
+	ModelUsage map[string]Usage // 按模型累计的用量，用于 /cost
+	JSONMode   bool             // 开启时请求 json_object 格式的回复
+
+	ModelAlias string // 通过 model_aliases 中的别名选择模型时的别名
+
+	AutoLang bool // 按用户消息的语言要求回复语言，见 -auto-lang
+
+	ErrorHistory []ErrorRecord // 本次会话的请求错误，供 /errors 查看
+
+	Branch   string            // 当前分支名，空表示 main
+	Branches map[string]Branch // /fork 创建的其他分支
+
+	// 可选的消息处理钩子：PreSend 在发送前处理最后一条用户消息，
+	// PostReceive 在回复写入历史和输出前处理回复内容
+	PreSend     func(Message) Message
+	PostReceive func(string) string
 }
 
 func main() {
+	// 先加载 .env，依赖环境变量的默认值在解析参数后再取
+	loadEnvironment()
 	flag.Parse()
+	applyEnvFlags()
+	setupLanguage()
+	if *listCommands {
+		printCommandList()
+		return
+	}
+	if flag.Arg(0) == "login" {
+		runLogin()
+		return
+	}
+	if *apiKey == "" {
+		*apiKey = os.Getenv("ABL_API_KEY")
+	}
+	loadKeychainKey()
+	if err := loadConfig(); err != nil {
+		fmt.Fprintf(os.Stderr, msg("加载配置文件失败: %v\n"), err)
+		os.Exit(1)
+	}
+	if err := setupRoleMap(); err != nil {
+		fmt.Fprintf(os.Stderr, msg("错误：%v\n"), err)
+		os.Exit(1)
+	}
 	validateConfig()
+	loadPromptFile()
+	if err := loadExamples(); err != nil {
+		fmt.Fprintf(os.Stderr, msg("错误：加载示例文件失败: %v\n"), err)
+		os.Exit(1)
+	}
+	if err := loadTools(); err != nil {
+		fmt.Fprintf(os.Stderr, msg("错误：加载工具定义失败: %v\n"), err)
+		os.Exit(1)
+	}
 
 	client := &http.Client{
 		Timeout: time.Duration(*timeoutSec) * time.Second,
 		Transport: &http.Transport{
-			MaxIdleConns:        10,
-			IdleConnTimeout:     30 * time.Second,
+			MaxIdleConns:        *maxIdleConns,
+			IdleConnTimeout:     time.Duration(*idleTimeoutSec) * time.Second,
 			DisableCompression:  false,
+			DisableKeepAlives:   *disableKeepAlive,
 		},
 	}
 
+	if *idleStreamTimeout > 0 {
+		// 整体超时会截断耗时较长的正常输出，流式阶段改由空闲超时控制
+		client.Timeout = 0
+		client.Transport.(*http.Transport).ResponseHeaderTimeout = time.Duration(*timeoutSec) * time.Second
+	}
+
+	if *connectTimeout > 0 {
+		// 只限制建立连接和等待响应头，开始输出后仍由 -timeout 控制
+		timeout := time.Duration(*connectTimeout) * time.Second
+		transport := client.Transport.(*http.Transport)
+		transport.DialContext = (&net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}).DialContext
+		transport.TLSHandshakeTimeout = timeout
+		transport.ResponseHeaderTimeout = timeout
+	}
+
+	if *metricsAddr != "" {
+		if err := startMetricsServer(*metricsAddr); err != nil {
+			fmt.Fprintf(os.Stderr, msg("错误：启动指标服务失败: %v\n"), err)
+			os.Exit(1)
+		}
+	}
+
+	if err := setupStreamOutput(); err != nil {
+		fmt.Fprintf(os.Stderr, msg("错误：%v\n"), err)
+		os.Exit(1)
+	}
+
+	cmdHistory, err := loadCmdHistory()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, msg("加载命令历史失败: %v\n"), err)
+		cmdHistory = []CmdEntry{}
+	}
+
 	chatState := &ChatState{
-		Model:       *defaultModel,
-		History:     []Message{{Role: "system", Content: "You are a helpful assistant."}},
-		CmdHistory:  []string{},
-		Client:      client,
-		Debug:       *enableDebug,
-		isSingleCmd: *command != "",
+		Model:        *defaultModel,
+		SystemPrompt: defaultSystemPrompt,
+		History:      []Message{{Role: "system", Content: defaultSystemPrompt}},
+		CmdHistory:   cmdHistory,
+		Client:       client,
+		Debug:        *enableDebug && !*quiet,
+		isSingleCmd:  *command != "",
+		Pinned:       map[int]bool{},
+		Aliases:      map[string]string{},
+	}
+	for name, expansion := range appConfig.Aliases {
+		chatState.Aliases[name] = expansion
 	}
 
-	if *command != "" {
-		if err := executeSingleCommand(chatState, *command); err != nil {
-			fmt.Fprintln(os.Stderr, "错误:", err)
+	if isFlagSet("thinking") {
+		thinking := *enableThinking
+		chatState.EnableThinking = &thinking
+	}
+	if isFlagSet("seed") {
+		seedValue := *seed
+		chatState.Seed = &seedValue
+	}
+	if isFlagSet("presence-penalty") {
+		penalty := *presencePenalty
+		chatState.PresencePenalty = &penalty
+	}
+	chatState.JSONMode = *jsonResponse
+	chatState.AutoLang = *autoLang
+	if isFlagSet("temperature") {
+		chatState.Temperature = floatPtr(*temperature)
+	}
+	if isFlagSet("top-p") {
+		chatState.TopP = floatPtr(*topP)
+	}
+	if model, ok := resolveModelAlias(*defaultModel); ok {
+		chatState.Model, chatState.ModelAlias = model, *defaultModel
+	}
+	applyModelProfile(chatState)
+
+	if *startupRole != "" {
+		if err := useRole(chatState, *startupRole); err != nil {
+			fmt.Fprintln(os.Stderr, err)
 			os.Exit(1)
 		}
-		return
+	}
+
+	installSignalHandler(chatState)
+
+	if *serveAddr != "" {
+		if err := runServer(chatState, *serveAddr); err != nil {
+			fmt.Fprintf(os.Stderr, msg("错误：启动服务失败: %v\n"), err)
+			exitProgram(chatState, 1)
+		}
+	}
+
+	if *command != "" {
+		chatState.historyMu.Lock()
+		err := executeSingleCommand(chatState, *command)
+		chatState.historyMu.Unlock()
+		if errors.Is(err, errStreamCancelled) {
+			select {} // 请求被信号取消，由信号处理保存对话并退出
+		}
+		if err != nil {
+			finishLine()
+			fmt.Fprintln(os.Stderr, msg("错误:"), err)
+			exitProgram(chatState, 1)
+		}
+		exitProgram(chatState, 0)
 	}
 
 	startInteractiveSession(chatState)
+	saveLastSession(chatState)
+	exitProgram(chatState, 0)
+}
+
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// 随机生成的 UUID v4。系统随机源不可用时返回错误，不生成可能重复的值
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// 退出前保存需要持久化的状态
+func exitProgram(state *ChatState, code int) {
+	if err := saveCmdHistory(state.CmdHistory); err != nil {
+		fmt.Fprintf(os.Stderr, msg("保存命令历史失败: %v\n"), err)
+	}
+	closeSink()
+	closeMetricsServer()
+	os.Exit(code)
+}
+
+// -f: 读取整个文件作为单命令模式的提问，内容原样发送
+func loadPromptFile() {
+	if *promptFile == "" {
+		return
+	}
+	if isFlagSet("c") {
+		fmt.Fprintln(os.Stderr, msg("错误：-c 和 -f 不能同时使用"))
+		os.Exit(1)
+	}
+
+	data, err := os.ReadFile(*promptFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, msg("错误：读取提问文件失败: %v\n"), err)
+		os.Exit(1)
+	}
+	if strings.TrimSpace(string(data)) == "" {
+		fmt.Fprintf(os.Stderr, msg("错误：提问文件 %s 为空\n"), *promptFile)
+		os.Exit(1)
+	}
+	*command = string(data)
 }
 
 func validateConfig() {
-	if *apiKey == "" {
-		fmt.Fprintln(os.Stderr, "错误：必须提供API密钥")
+	if *apiKey == "" && *replayFile == "" {
+		fmt.Fprintln(os.Stderr, msg("错误：必须提供API密钥"))
 		flag.Usage()
 		os.Exit(1)
 	}
+
+	if isFlagSet("presence-penalty") && !validPenalty(*presencePenalty) {
+		fmt.Fprintln(os.Stderr, msg("错误：-presence-penalty 取值范围为 -2 到 2"))
+		os.Exit(1)
+	}
+
+	if isFlagSet("temperature") && (*temperature < 0 || *temperature > 2) {
+		fmt.Fprintln(os.Stderr, msg("错误：-temperature 取值范围为 0 到 2"))
+		os.Exit(1)
+	}
+	if isFlagSet("top-p") && (*topP <= 0 || *topP > 1) {
+		fmt.Fprintln(os.Stderr, msg("错误：-top-p 取值范围为大于 0 且不超过 1"))
+		os.Exit(1)
+	}
+
+	if isFlagSet("top-logprobs") && (*topLogProbs < 0 || *topLogProbs > 20) {
+		fmt.Fprintln(os.Stderr, msg("错误：-top-logprobs 取值范围为 0 到 20"))
+		os.Exit(1)
+	}
+
+	if err := compileStopPattern(); err != nil {
+		fmt.Fprintf(os.Stderr, msg("错误：%v\n"), err)
+		os.Exit(1)
+	}
+
+	if err := validBannerMode(); err != nil {
+		fmt.Fprintf(os.Stderr, msg("错误：%v\n"), err)
+		os.Exit(1)
+	}
+
+	if err := validMergeRolesMode(); err != nil {
+		fmt.Fprintf(os.Stderr, msg("错误：%v\n"), err)
+		os.Exit(1)
+	}
+
+	if err := validStreamUsageMode(); err != nil {
+		fmt.Fprintf(os.Stderr, msg("错误：%v\n"), err)
+		os.Exit(1)
+	}
+
+	if _, ok := requestEncoders[*requestFormat]; !ok {
+		fmt.Fprintf(os.Stderr, msg("错误：不支持的请求格式 %q，可选 openai|anthropic\n"), *requestFormat)
+		os.Exit(1)
+	}
+
+	u, err := url.Parse(*apiEndpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		fmt.Fprintf(os.Stderr, msg("错误：无效的API地址 %q，需要以 http:// 或 https:// 开头并包含主机名\n"), *apiEndpoint)
+		os.Exit(1)
+	}
+
+	if !*noKeyCheck && *apiKey != "" {
+		warnKeyFormat(u.Hostname(), *apiKey)
+	}
+
+	if *checkEndpoint {
+		if err := probeEndpoint(*apiEndpoint); err != nil {
+			fmt.Fprintf(os.Stderr, msg("错误：API地址不可达: %v\n"), err)
+			os.Exit(1)
+		}
+	}
+}
+
+// 已知服务商的密钥格式，自建网关不做检查
+var keyPatterns = []struct {
+	host    string
+	pattern *regexp.Regexp
+}{
+	{"dashscope.aliyuncs.com", regexp.MustCompile(`^sk-[0-9a-zA-Z]{32}$`)},
+	{"dashscope-intl.aliyuncs.com", regexp.MustCompile(`^sk-[0-9a-zA-Z]{32}$`)},
+	{"api.openai.com", regexp.MustCompile(`^sk-[0-9a-zA-Z_-]{20,}$`)},
+	{"api.anthropic.com", regexp.MustCompile(`^sk-ant-[0-9a-zA-Z_-]+$`)},
+}
+
+// 密钥格式不符时只警告，不阻止启动
+func warnKeyFormat(host, key string) {
+	for _, kp := range keyPatterns {
+		if host == kp.host && !kp.pattern.MatchString(key) {
+			fmt.Fprintf(os.Stderr, msg("警告：API密钥格式与 %s 的常见格式不符，请检查是否有误(可用 -no-key-check 跳过)\n"), host)
+			return
+		}
+	}
+}
+
+// 用 HEAD 请求快速确认地址可达，任何HTTP响应都视为可达
+func probeEndpoint(endpoint string) error {
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Head(endpoint)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
 }
 
 func executeSingleCommand(state *ChatState, cmd string) error {
 	cmd = strings.TrimSpace(cmd)
 	if cmd == "" {
-		return errors.New("空命令")
+		return errors.New(msg("空命令"))
 	}
 
-	state.CmdHistory = append(state.CmdHistory, cmd)
+	recordCommand(state, cmd)
+	cmd = expandAlias(state, cmd)
 
 	if handleCommand(cmd, state) {
 		return nil
 	}
+	if model, rest, ok := splitModelPrefix(cmd); ok {
+		defer useModelForTurn(state, model)()
+		cmd = rest
+	}
 
-	state.History = append(state.History, Message{Role: "user", Content: cmd})
-	_, err := processAIResponse(state, *enableStream)
+	state.History = append(state.History, newMessage("user", prepareUserMessage(state, cmd)))
+	_, err := processAIResponse(state, *enableStream && !*jsonOutput)
+	if *jsonOutput {
+		printJSONResult(state, err)
+	}
 	return err
 }
 
+// 用户消息加入历史前的预处理
+func prepareUserMessage(state *ChatState, input string) string {
+	content := assembleUserMessage(state, input)
+	state.Pending = nil
+	state.PrevReply = ""
+	if state.Debug && content != input {
+		fmt.Printf(msg("[DEBUG] 实际发送的用户消息: %q\n"), content)
+	}
+	return content
+}
+
+// 处理后的消息与上一条用户消息完全相同，单命令模式不检查
+func isDuplicateMessage(state *ChatState, input string) bool {
+	if state.isSingleCmd {
+		return false
+	}
+	content := assembleUserMessage(state, input)
+	for i := len(state.History) - 1; i > 0; i-- {
+		if state.History[i].Role == "user" {
+			return state.History[i].Content == content
+		}
+	}
+	return false
+}
+
+// 变量替换、前后缀和待附加内容处理后的用户消息，不修改状态
+func assembleUserMessage(state *ChatState, input string) string {
+	content := *messagePrefix + substituteVars(state, input) + *messageSuffix
+	if len(state.Pending) > 0 {
+		content = strings.Join(state.Pending, "\n\n") + "\n\n" + content
+	}
+	return content
+}
+
+// 单命令模式由 -stream 决定，交互模式总是流式输出
+func streamMode(state *ChatState) bool {
+	if state.isSingleCmd {
+		return *enableStream
+	}
+	return true
+}
+
 func startInteractiveSession(state *ChatState) {
+	cycler := &modelCycler{}
 	rl, err := readline.NewEx(&readline.Config{
-		Prompt:          "> ",
+		Prompt:          promptString(state),
 		HistoryFile:     getHistoryFilePath(),
 		AutoComplete:    getCompleter(),
 		InterruptPrompt: "^C",
 		EOFPrompt:       "exit",
+		// Ctrl+N 循环切换模型，在 readline 的协程中调用，只更新提示符，回车后由主循环切换
+		FuncFilterInputRune: func(r rune) (rune, bool) {
+			if r == readline.CharNext {
+				cycler.next(state.rl)
+				return r, false
+			}
+			return r, true
+		},
 	})
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "初始化命令行失败: %v\n", err)
+		fmt.Fprintf(os.Stderr, msg("初始化命令行失败: %v\n"), err)
 		os.Exit(1)
 	}
 	defer rl.Close()
+	state.rl = rl
+
+	// 只在等待输入时释放，信号处理不会读到修改中的 History
+	state.historyMu.Lock()
+	defer state.historyMu.Unlock()
 
 	printWelcomeMessage(state)
+	if *continueSession {
+		continueLastSession(state)
+	} else {
+		offerAutosaveRestore(state)
+	}
+
+	if *initialPrompt != "" {
+		processInput(state, *initialPrompt)
+	}
 
 	for {
+		cycler.reset(state.Model, promptString(state))
+		state.historyMu.Unlock()
 		input, err := rl.Readline()
+		state.historyMu.Lock()
+		if model := cycler.take(); model != "" {
+			updatePrompt(state)
+			if model != state.Model {
+				switchModel(state, model)
+			}
+		}
 		if err != nil {
 			if err == readline.ErrInterrupt {
 				if len(input) == 0 {
@@ -152,27 +792,49 @@ func startInteractiveSession(state *ChatState) {
 			} else if err == io.EOF {
 				break
 			}
-			fmt.Fprintf(os.Stderr, "读取输入错误: %v\n", err)
+			fmt.Fprintf(os.Stderr, msg("读取输入错误: %v\n"), err)
 			continue
 		}
 
-		input = strings.TrimSpace(input)
-		if input == "" {
-			continue
-		}
+		processInput(state, input)
+	}
+}
 
-		state.CmdHistory = append(state.CmdHistory, input)
+// 处理交互模式下的一行输入
+func processInput(state *ChatState, input string) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return
+	}
 
-		if handleCommand(input, state) {
-			continue
-		}
+	recordCommand(state, input)
+	input = expandAlias(state, input)
 
-		state.History = append(state.History, Message{Role: "user", Content: input})
-		if _, err := processAIResponse(state, true); err != nil {
-			fmt.Fprintf(os.Stderr, "\n错误: %v\n", err)
+	if handleCommand(input, state) {
+		return
+	}
+	if model, rest, ok := splitModelPrefix(input); ok {
+		defer useModelForTurn(state, model)()
+		input = rest
+	}
+	if *warnDuplicate && isDuplicateMessage(state, input) &&
+		!confirm(state, msg("与上一条用户消息相同，仍要发送? [y/N] ")) {
+		return
+	}
+
+	state.History = append(state.History, newMessage("user", prepareUserMessage(state, input)))
+	if _, err := processAIResponse(state, true); err != nil {
+		if errors.Is(err, errStreamCancelled) {
+			discardStreamDisplay()
 		}
-		fmt.Println()
+		finishLine()
+		if errors.Is(err, errStreamCancelled) {
+			refineAfterCancel(state)
+			return
+		}
+		fmt.Fprintf(os.Stderr, msg("错误: %v\n"), err)
 	}
+	finishLine()
 }
 
 func getHistoryFilePath() string {
@@ -182,110 +844,416 @@ func getHistoryFilePath() string {
 	return os.TempDir() + "/abls_history.txt"
 }
 
-func getCompleter() *readline.PrefixCompleter {
-	return readline.NewPrefixCompleter(
-		readline.PcItem("/model",
-			readline.PcItem("qwen-plus"),
-			readline.PcItem("qwen-max"),
-			readline.PcItem("qwen-turbo"),
-			readline.PcItem("deepseek-r1"),
-			readline.PcItem("deepseek-v3"),
-		),
-		readline.PcItem("/debug"),
-		readline.PcItem("/reset"),
-		readline.PcItem("/help"),
-		readline.PcItem("/history"),
-		readline.PcItem("exit"),
-	)
-}
-
 func handleCommand(input string, state *ChatState) bool {
 	switch {
 	case input == "exit" || input == "/quit":
-		os.Exit(0)
+		saveLastSession(state)
+		exitProgram(state, 0)
 	case input == "/reset":
 		resetConversation(state)
 		return true
+	case input == "/retry-with" || strings.HasPrefix(input, "/retry-with "):
+		retryWith(input, state)
+		return true
+	case input == "/retry":
+		retryLastReply(state)
+		return true
+	case input == "/diff":
+		showReplyDiff(state)
+		return true
+	case input == "/replay-last" || strings.HasPrefix(input, "/replay-last "):
+		replayLast(input, state)
+		return true
+	case input == "/replay-from" || strings.HasPrefix(input, "/replay-from "):
+		replayFrom(input, state)
+		return true
+	case input == "/replay" || strings.HasPrefix(input, "/replay "):
+		handleReplay(input, state)
+		return true
+	case input == "/export" || strings.HasPrefix(input, "/export "):
+		handleExport(input, state)
+		return true
 	case strings.HasPrefix(input, "/model"):
 		handleModelSwitch(input, state)
 		return true
+	case input == "/format" || strings.HasPrefix(input, "/format "):
+		handleResponseFormat(input, state)
+		return true
 	case input == "/debug":
 		toggleDebugMode(state)
 		return true
+	case input == "/think":
+		toggleThinking(state)
+		return true
+	case strings.HasPrefix(input, "/presence"):
+		handlePresencePenalty(input, state)
+		return true
 	case input == "/help":
 		printHelp()
 		return true
-	case input == "/history":
-		showCommandHistory(state)
+	case input == "/info":
+		printSessionInfo(state)
+		return true
+	case input == "/title" || strings.HasPrefix(input, "/title "):
+		handleTitleCommand(input, state)
+		return true
+	case input == "/stats":
+		showStats(state)
+		return true
+	case input == "/tool-result" || strings.HasPrefix(input, "/tool-result "):
+		handleToolResult(input, state)
+		return true
+	case input == "/autolang" || strings.HasPrefix(input, "/autolang "):
+		handleAutoLang(input, state)
+		return true
+	case input == "/errors" || strings.HasPrefix(input, "/errors "):
+		handleErrorsCommand(input, state)
+		return true
+	case input == "/lint":
+		lintHistory(state)
+		return true
+	case input == "/cost":
+		showCost(state)
+		return true
+	case input == "/benchmark" || strings.HasPrefix(input, "/benchmark "):
+		runBenchmark(input, state)
+		return true
+	case input == "/history-search" || strings.HasPrefix(input, "/history-search "):
+		searchCommandHistory(input, state)
+		return true
+	case input == "/history-run" || strings.HasPrefix(input, "/history-run "):
+		rerunCommandHistory(input, state)
+		return true
+	case input == "/history" || strings.HasPrefix(input, "/history "):
+		handleHistoryCommand(input, state)
+		return true
+	case input == "/prompt-preview" || strings.HasPrefix(input, "/prompt-preview "):
+		previewPrompt(input, state)
+		return true
+	case input == "/prompt" || strings.HasPrefix(input, "/prompt "):
+		handlePromptCommand(input, state)
+		return true
+	case input == "/overlay" || strings.HasPrefix(input, "/overlay "):
+		handleOverlayCommand(input, state)
+		return true
+	case input == "/role" || strings.HasPrefix(input, "/role "):
+		handleRoleCommand(input, state)
+		return true
+	case *allowShell && strings.HasPrefix(input, "!"): // 未开启时以 ! 开头的输入按普通消息发送
+		runShellCommand(input, state)
+		return true
+	case input == "/pick" || strings.HasPrefix(input, "/pick "):
+		pickModel(input, state)
+		return true
+	case strings.HasPrefix(input, "/compare"):
+		handleCompare(input, state)
+		return true
+	case input == "/context" || strings.HasPrefix(input, "/context "):
+		handleContextCommand(input, state)
+		return true
+	case input == "/pins":
+		showPins(state)
+		return true
+	case input == "/summarize" || strings.HasPrefix(input, "/summarize "):
+		handleSummarize(input, state)
+		return true
+	case input == "/set" || strings.HasPrefix(input, "/set "):
+		handleSetVar(input, state)
+		return true
+	case input == "/unset" || strings.HasPrefix(input, "/unset "):
+		handleUnsetVar(input, state)
+		return true
+	case input == "/vars":
+		listVars(state)
+		return true
+	case input == "/alias" || strings.HasPrefix(input, "/alias "):
+		handleAliasCommand(input, state)
+		return true
+	case input == "/fork" || strings.HasPrefix(input, "/fork "):
+		handleFork(input, state)
+		return true
+	case input == "/branch" || strings.HasPrefix(input, "/branch "):
+		handleBranchCommand(input, state)
+		return true
+	case input == "/attach":
+		attachClipboard(state)
+		return true
+	case input == "/wait" || strings.HasPrefix(input, "/wait "):
+		handleWait(input)
+		return true
+	case input == "/pin" || strings.HasPrefix(input, "/pin ") || strings.HasPrefix(input, "/unpin "):
+		handlePinCommand(input, state)
 		return true
 	}
 	return false
 }
 
 func resetConversation(state *ChatState) {
-	state.History = []Message{{Role: "system", Content: "You are a helpful assistant."}}
+	state.History = []Message{{Role: "system", Content: state.SystemPrompt}}
+	state.Pinned = map[int]bool{}
+	state.Title = ""
+	updatePrompt(state)
 	state.LastRequestID = ""
-	fmt.Println("对话历史已重置")
+	fmt.Println(msg("对话历史已重置"))
 }
 
 func handleModelSwitch(input string, state *ChatState) {
-	parts := strings.Split(input, " ")
+	parts := strings.Fields(input)
 	if len(parts) < 2 {
-		fmt.Printf("当前模型: %s\n可用模型: qwen-plus, qwen-max, qwen-turbo, deepseek-r1, deepseek-v3\n", state.Model)
+		fmt.Printf(msg("当前模型: %s\n可用模型: %s\n"), modelLabel(state), availableModelsText())
+		if aliases := modelAliasesText(); aliases != "" {
+			fmt.Printf(msg("模型别名: %s\n"), aliases)
+		}
+		return
+	}
+
+	if parts[1] == "info" {
+		name := state.Model
+		if len(parts) > 2 {
+			name = parts[2]
+		}
+		printModelInfo(name)
 		return
 	}
 
 	newModel := parts[1]
-	switch newModel {
-	case "qwen-plus", "qwen-max", "qwen-turbo", "deepseek-r1", "deepseek-v3":
-		state.Model = newModel
-		fmt.Printf("已切换模型为: %s\n", state.Model)
-	default:
-		fmt.Println("错误：不支持的模型")
+	_, isAlias := resolveModelAlias(newModel)
+	if _, ok := lookupModel(newModel); ok || isAlias {
+		switchModel(state, newModel)
+	} else {
+		fmt.Println(msg("错误：不支持的模型"))
+	}
+}
+
+func switchModel(state *ChatState, model string) {
+	state.Model, state.ModelAlias = model, ""
+	if resolved, ok := resolveModelAlias(model); ok {
+		state.Model, state.ModelAlias = resolved, model
+	}
+	applyModelProfile(state)
+	fmt.Printf(msg("已切换模型为: %s\n"), modelLabel(state))
+}
+
+// Ctrl+N 选择的模型。按键在 readline 的协程中处理，不能直接修改 ChatState：
+// 这里只记录选择并在提示符中显示，主循环读完这一行输入后再切换
+type modelCycler struct {
+	mu      sync.Mutex
+	current string // 等待输入前主循环设置的当前模型和提示符
+	prompt  string
+	pending string
+}
+
+func (c *modelCycler) reset(model, prompt string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.current, c.prompt, c.pending = model, prompt, ""
+}
+
+// 按补全列表中的顺序选择下一个模型
+func (c *modelCycler) next(rl *readline.Instance) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	base := c.pending
+	if base == "" {
+		base = c.current
+	}
+	names := knownModelNames()
+	next := 0
+	for i, name := range names {
+		if name == base {
+			next = (i + 1) % len(names)
+			break
+		}
+	}
+	c.pending = names[next]
+	if rl != nil {
+		rl.SetPrompt(strings.TrimSuffix(c.prompt, "> ") + "@" + c.pending + "> ")
+		rl.Refresh()
 	}
 }
 
+func (c *modelCycler) take() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	model := c.pending
+	c.pending = ""
+	return model
+}
+
 func toggleDebugMode(state *ChatState) {
 	state.Debug = !state.Debug
-	fmt.Printf("调试模式 %v\n", state.Debug)
+	fmt.Printf(msg("调试模式 %v\n"), state.Debug)
+}
+
+func toggleThinking(state *ChatState) {
+	thinking := state.EnableThinking == nil || !*state.EnableThinking
+	state.EnableThinking = &thinking
+	fmt.Printf(msg("深度思考 %v\n"), thinking)
+}
+
+func validPenalty(v float64) bool {
+	return v >= -2 && v <= 2
+}
+
+func handlePresencePenalty(input string, state *ChatState) {
+	parts := strings.Fields(input)
+	if len(parts) < 2 {
+		if state.PresencePenalty == nil {
+			fmt.Println(msg("存在惩罚: 未设置"))
+		} else {
+			fmt.Printf(msg("存在惩罚: %g\n"), *state.PresencePenalty)
+		}
+		return
+	}
+
+	if parts[1] == "off" {
+		state.PresencePenalty = nil
+		fmt.Println(msg("已取消存在惩罚"))
+		return
+	}
+
+	v, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil || !validPenalty(v) {
+		fmt.Println(msg("错误：存在惩罚取值范围为 -2 到 2"))
+		return
+	}
+	state.PresencePenalty = &v
+	fmt.Printf(msg("已设置存在惩罚: %g\n"), v)
+}
+
+func handleResponseFormat(input string, state *ChatState) {
+	parts := strings.Fields(input)
+	if len(parts) < 2 {
+		if state.JSONMode {
+			fmt.Println(msg("回复格式: json"))
+		} else {
+			fmt.Println(msg("回复格式: text"))
+		}
+		return
+	}
+
+	switch parts[1] {
+	case "json":
+		state.JSONMode = true
+	case "text":
+		state.JSONMode = false
+	default:
+		fmt.Println(msg("用法: /format json|text"))
+		return
+	}
+	fmt.Printf(msg("已设置回复格式: %s\n"), parts[1])
 }
 
-func showCommandHistory(state *ChatState) {
-	if len(state.CmdHistory) == 0 {
-		fmt.Println("暂无历史记录")
+// 暂停指定秒数，每满一秒输出一个点，便于脚本驱动交互会话
+func handleWait(input string) {
+	parts := strings.Fields(input)
+	if len(parts) != 2 {
+		fmt.Println(msg("用法: /wait <秒数>"))
+		return
+	}
+	seconds, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil || seconds <= 0 {
+		fmt.Println(msg("错误：等待时间必须为正数"))
 		return
 	}
 
-	fmt.Println("命令历史:")
-	for i, cmd := range state.CmdHistory {
-		fmt.Printf("%4d: %s\n", i+1, cmd)
+	deadline := time.Now().Add(time.Duration(seconds * float64(time.Second)))
+	for remaining := time.Until(deadline); remaining > 0; remaining = time.Until(deadline) {
+		if remaining < time.Second {
+			time.Sleep(remaining)
+			break
+		}
+		time.Sleep(time.Second)
+		if !*quiet {
+			fmt.Print(".")
+		}
+	}
+	if !*quiet && seconds >= 1 {
+		fmt.Println()
+	}
+}
+
+// 开启深度思考或指定 -show-reasoning 时显示思考过程
+func shouldShowReasoning(state *ChatState) bool {
+	if *quiet || state.serveOutput != nil {
+		return false
 	}
+	return *showReasoning || (state.EnableThinking != nil && *state.EnableThinking)
 }
 
 func processAIResponse(state *ChatState, streamOutput bool) (string, error) {
 	startTime := time.Now()
-	
-	if streamOutput && !state.isSingleCmd {
-		fmt.Printf("AI(%s): ", state.Model)
+	trimHistory(state)
+
+	if last := len(state.History) - 1; state.PreSend != nil && state.History[last].Role == "user" {
+		state.History[last] = state.PreSend(state.History[last])
+	}
+
+	if streamOutput && !state.isSingleCmd && !*quiet {
+		printLive(fmt.Sprintf("AI(%s): ", state.Model))
 	}
 
-	aiReply, requestID, err := streamChatCompletion(state, streamOutput)
+	messages := buildMessages(state)
+	if state.AutoLang {
+		messages = withLanguageHint(messages)
+	}
+	result, err := streamChatCompletion(state, state.Model, messages, streamOutput)
+	state.LastResult = result
 	if err != nil {
 		return "", err
 	}
 
-	state.LastRequestID = requestID
-	state.History = append(state.History, Message{
-		Role:    "assistant",
-		Content: aiReply,
-	})
+	aiReply := result.Content
+	if state.PostReceive != nil {
+		aiReply = state.PostReceive(aiReply)
+	}
+	if *trimReplies {
+		aiReply = trimReply(aiReply)
+		for i, choice := range result.Choices {
+			result.Choices[i] = trimReply(choice)
+		}
+	}
+	state.LastRequestID = result.RequestID
+	recordUsage(state, state.Model, result.Usage)
+	if state.JSONMode && !json.Valid([]byte(strings.TrimSpace(aiReply))) {
+		finishLine()
+		fmt.Fprintln(os.Stderr, msg("警告：JSON模式下的回复不是合法JSON"))
+	}
+	state.Stats = append(state.Stats, newTurnStat(startTime, result))
+	reply := newMessage("assistant", aiReply)
+	reply.ToolCalls = result.ToolCalls
+	state.History = append(state.History, reply)
+	waitStreamDisplay()
 
-	if state.isSingleCmd {
+	// JSON 模式由 executeSingleCommand 统一输出
+	if state.isSingleCmd && *jsonOutput {
+		return aiReply, nil
+	}
+
+	if !streamOutput && result.Reasoning != "" && shouldShowReasoning(state) {
+		fmt.Println(colorize(ansiDim, result.Reasoning))
+		fmt.Println()
+	}
+
+	if len(result.Choices) > 1 {
+		printChoices(result.Choices)
+		if !*quiet {
+			fmt.Println(msg("(已将回答 1 加入对话历史)"))
+		}
+	} else if state.isSingleCmd {
 		if !streamOutput {
-			fmt.Println(aiReply)
+			printReply(aiReply)
 		}
-	} else if !streamOutput {
-		fmt.Println(aiReply)
+	} else if !streamOutput && aiReply != "" {
+		printReply(aiReply)
+	}
+
+	if len(result.ToolCalls) > 0 {
+		printToolCalls(state, result.ToolCalls)
+	}
+
+	if *showUsage && !*quiet {
+		printTurnUsage(result.Usage)
 	}
 
 	if state.Debug {
@@ -295,143 +1263,485 @@ func processAIResponse(state *ChatState, streamOutput bool) (string, error) {
 	return aiReply, nil
 }
 
-func streamChatCompletion(state *ChatState, streamOutput bool) (string, string, error) {
+// 多个回答时逐条编号输出
+func printChoices(choices []string) {
+	fmt.Println()
+	for i, choice := range choices {
+		fmt.Printf(msg("--- 回答 %d ---\n"), i+1)
+		printReply(choice)
+	}
+}
+
+func streamChatCompletion(state *ChatState, model string, messages []Message, streamOutput bool) (*StreamResult, error) {
+	startTime := time.Now()
+	result, err := requestChatCompletion(state, model, messages, streamOutput)
+	metrics.observe(model, time.Since(startTime), result, err)
+	recordError(state, model, result, err)
+	if isModelNotFound(err) {
+		err = &modelNotFoundError{model: model, err: err}
+	}
+	return result, err
+}
+
+func requestChatCompletion(state *ChatState, model string, messages []Message, streamOutput bool) (*StreamResult, error) {
+	if streamOutput && state.serveOutput == nil {
+		defer endSinkTurn()
+	}
+	if *idempotencyKey {
+		key, err := newUUID()
+		if err != nil {
+			return nil, fmt.Errorf(msg("生成 Idempotency-Key 失败: %w"), err)
+		}
+		state.idempotencyKey = key
+		if state.Debug {
+			fmt.Printf("[DEBUG] Idempotency-Key: %s\n", state.idempotencyKey)
+		}
+	}
+
+	if state.streamUnsupported {
+		return sendNonStreamRequest(state, model, messages, streamOutput)
+	}
+
+	result, err := sendStreamRequest(state, model, messages, streamOutput)
+	if *allowNonStreamFallback && streamUnsupported(err) {
+		fallbackToNonStream(state, err)
+		return sendNonStreamRequest(state, model, messages, streamOutput)
+	}
+	if *resumeAttempts <= 0 || *completionCount > 1 {
+		return result, err
+	}
+
+	// 携带已收到的内容续传，续传部分收齐后再输出以便去重
+	for attempt := 1; attempt <= *resumeAttempts && streamInterrupted(result, err); attempt++ {
+		partial := result.Content
+		finishLine()
+		fmt.Fprintf(os.Stderr, msg("[流中断，第 %d 次续传] %v\n"), attempt, err)
+
+		resumed := append(append([]Message{}, messages...), Message{Role: "assistant", Content: partial})
+		next, nextErr := sendStreamRequest(state, model, resumed, false)
+		if next == nil {
+			return result, nextErr
+		}
+
+		continuation := strings.TrimPrefix(next.Content, partial)
+		if streamOutput {
+			writeReplyChunk(state, continuation)
+		}
+		next.Content = partial + continuation
+		next.Reasoning = result.Reasoning + next.Reasoning
+		next.Choices = []string{next.Content}
+		next.LogProbs = append(result.LogProbs, next.LogProbs...)
+		if next.RequestID == "" {
+			next.RequestID = result.RequestID
+		}
+		result, err = next, nextErr
+	}
+	return result, err
+}
+
+// -flush 时将已写出的内容同步到输出端，输出到管道时同步失败可忽略
+func flushStdout() {
+	if *flushOutput {
+		os.Stdout.Sync()
+	}
+}
+
+func streamInterrupted(result *StreamResult, err error) bool {
+	if result == nil || result.Content == "" {
+		return false
+	}
+	return errors.Is(err, errStreamBroken) || (err == nil && !result.Done)
+}
+
+func sendStreamRequest(state *ChatState, model string, messages []Message, streamOutput bool) (*StreamResult, error) {
+	payload := buildPayload(state, model, messages)
+	payload.Stream = true
+	if streamUsageEnabled() {
+		payload.StreamOptions = &StreamOptions{IncludeUsage: true}
+	}
+
+	reqCtx, done := cancellableRequest(state)
+	defer done()
+	ctx, cancel := context.WithCancel(reqCtx)
+	defer cancel()
+
+	sentAt := time.Now()
+	if *replayFile != "" {
+		body, err := openReplay(ctx, *replayFile)
+		if err != nil {
+			return nil, err
+		}
+		result, err := processStreamResponse(body, state, streamOutput, cancel)
+		if result != nil {
+			result.StatusCode = http.StatusOK
+			if !result.firstTokenAt.IsZero() {
+				result.TTFT = result.firstTokenAt.Sub(sentAt)
+			}
+		}
+		return result, cancelledErr(ctx, err)
+	}
+
+	resp, body, err := postChatRequest(ctx, state, payload)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if err != nil {
+		if resp != nil {
+			return &StreamResult{StatusCode: resp.StatusCode}, err
+		}
+		return nil, cancelledErr(ctx, err)
+	}
+
+	if *recordFile != "" {
+		recorder, f, err := newRecordingReader(body, *recordFile)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		body = recorder
+	}
+
+	if *idleStreamTimeout > 0 {
+		idle := newIdleTimeoutReader(body, time.Duration(*idleStreamTimeout)*time.Second, cancel)
+		defer idle.Stop()
+		body = idle
+	}
+
+	result, err := processStreamResponse(body, state, streamOutput, cancel)
+	if result != nil {
+		result.StatusCode = resp.StatusCode
+		if !result.firstTokenAt.IsZero() {
+			result.TTFT = result.firstTokenAt.Sub(sentAt)
+		}
+	}
+	return result, cancelledErr(ctx, err)
+}
+
+// 未指定 -user 时使用系统用户名，指定为空时不发送
+func requestUser() string {
+	if isFlagSet("user") {
+		return *userID
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
+
+func buildPayload(state *ChatState, model string, messages []Message) StreamRequest {
 	payload := StreamRequest{
-		Model:    state.Model,
-		Messages: state.History,
-		Stream:   true,
+		Model:    model,
+		Messages: withoutTimestamps(messages),
+		User:     requestUser(),
+		Tools:    toolDefs,
+
+		EnableThinking: state.EnableThinking,
+		Seed:           state.Seed,
+
+		PresencePenalty: state.PresencePenalty,
+		Temperature:     state.Temperature,
+		TopP:            state.TopP,
+	}
+	if *completionCount > 1 {
+		n := *completionCount
+		payload.N = &n
+	}
+	if state.JSONMode {
+		payload.ResponseFormat = &ResponseFormat{Type: "json_object"}
 	}
+	if *logProbs || isFlagSet("top-logprobs") {
+		enabled := true
+		payload.LogProbs = &enabled
+	}
+	if isFlagSet("top-logprobs") {
+		n := *topLogProbs
+		payload.TopLogProbs = &n
+	}
+	return payload
+}
 
-	jsonData, err := json.Marshal(payload)
+// 发送请求并返回解压后的响应体。状态码非200时 resp 仍返回以便读取状态码，
+// 调用方负责关闭 resp.Body
+func postChatRequest(ctx context.Context, state *ChatState, payload StreamRequest) (*http.Response, io.Reader, error) {
+	jsonData, err := selectedEncoder().Encode(payload)
 	if err != nil {
-		return "", "", fmt.Errorf("JSON编码失败: %w", err)
+		return nil, nil, fmt.Errorf(msg("JSON编码失败: %w"), err)
+	}
+	if *maxRequestBytes > 0 && len(jsonData) > *maxRequestBytes {
+		return nil, nil, fmt.Errorf(msg("请求体 %d 字节超出限制 %d 字节(-max-request-bytes)，可用 /context clear 移除上下文文件、/summarize 代替完整文件或 /reset 清除历史"),
+			len(jsonData), *maxRequestBytes)
 	}
 
 	if state.Debug {
-		fmt.Printf("\n[DEBUG] 请求体: %s\n", jsonData)
+		fmt.Printf(msg("\n[DEBUG] 请求体: %s\n"), jsonData)
 	}
 
-	req, err := http.NewRequest("POST", *apiEndpoint, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", *apiEndpoint, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", "", fmt.Errorf("创建请求失败: %w", err)
+		return nil, nil, fmt.Errorf(msg("创建请求失败: %w"), err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+*apiKey)
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	if *requestFormat == "anthropic" {
+		setAnthropicHeaders(req)
+	} else {
+		req.Header.Set(*authHeader, *authPrefix+*apiKey)
+	}
+	if state.idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", state.idempotencyKey)
+	}
+	signRequest(req, jsonData)
 
 	resp, err := state.Client.Do(req)
+	if *verbose {
+		logHTTPExchange(req, resp, err)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf(msg("请求发送失败: %w"), err)
+	}
+
+	body, err := decodeBody(resp)
 	if err != nil {
-		return "", "", fmt.Errorf("请求发送失败: %w", err)
+		return resp, nil, err
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", "", fmt.Errorf("API错误 %d: %s", resp.StatusCode, string(body))
+		data, _ := io.ReadAll(body)
+		if state.Debug {
+			fmt.Printf(msg("\n[DEBUG] 错误响应体: %s\n"), data)
+		}
+		return resp, nil, parseAPIError(resp.StatusCode, data)
 	}
+	return resp, body, nil
+}
 
-	return processStreamResponse(resp.Body, state.Debug, streamOutput)
+// 网关排查时关注的响应头
+var verboseHeaders = []string{
+	"X-Request-Id",
+	"X-Dashscope-Request-Id",
+	"X-RateLimit-Remaining-Requests",
+	"X-RateLimit-Remaining-Tokens",
+}
+
+func logHTTPExchange(req *http.Request, resp *http.Response, err error) {
+	fmt.Fprintf(os.Stderr, "[VERBOSE] %s %s\n", req.Method, req.URL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, msg("[VERBOSE] 请求失败: %v\n"), err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, msg("[VERBOSE] 响应状态: %s\n"), resp.Status)
+	for _, name := range verboseHeaders {
+		if v := resp.Header.Get(name); v != "" {
+			fmt.Fprintf(os.Stderr, "[VERBOSE] %s: %s\n", name, v)
+		}
+	}
 }
 
-func processStreamResponse(body io.Reader, debug, streamOutput bool) (string, string, error) {
-	reader := bufio.NewReader(body)
+// cancel 用于 -stop-regex 命中后在本地中止流
+func processStreamResponse(body io.Reader, state *ChatState, streamOutput bool, cancel func()) (*StreamResult, error) {
+	// 多个回答交错到达，仅在单回答时实时输出
+	expected := max(*completionCount, 1)
+	liveOutput := streamOutput && expected == 1
+	showThinking := shouldShowReasoning(state)
+
 	var (
-		fullResponse strings.Builder
-		requestID    string
+		responses    = map[int]*strings.Builder{}
+		lastChunk    = map[int]string{}
+		thinkTags    = map[int]*thinkFilter{}
+		stopped      = map[int]bool{}
+		reasoning    strings.Builder
+		logProbs     []TokenLogProb
+		toolCalls    []ToolCall
+		finished     int
+		inReasoning  bool
+		firstTokenAt time.Time
 	)
 
-	for {
-		line, err := reader.ReadBytes('\n')
-		if err != nil {
-			if errors.Is(err, io.EOF) {
-				break
+	buildResult := func(final StreamEvent) *StreamResult {
+		result := &StreamResult{
+			Reasoning: reasoning.String(),
+			RequestID: final.RequestID,
+			Usage:     final.Usage,
+			Done:      final.Done || finished >= expected || len(stopped) >= expected,
+			LogProbs:  logProbs,
+			ToolCalls: toolCalls,
+
+			firstTokenAt: firstTokenAt,
+		}
+		if first, ok := responses[0]; ok {
+			result.Content = first.String()
+		}
+		for i := 0; i < len(responses); i++ {
+			if builder, ok := responses[i]; ok {
+				result.Choices = append(result.Choices, builder.String())
 			}
-			return "", "", fmt.Errorf("读取流失败: %w", err)
 		}
+		return result
+	}
 
-		if len(line) < 6 || !bytes.HasPrefix(line, []byte("data: ")) {
-			continue
+	emit := func(index int, content, thinking string) {
+		if thinking != "" && index == 0 {
+			if liveOutput && showThinking {
+				if !inReasoning {
+					printLive(colorize(ansiDim, msg("[思考] ")))
+					inReasoning = true
+				}
+				printLive(colorize(ansiDim, thinking))
+			}
+			reasoning.WriteString(thinking)
 		}
 
-		if bytes.Equal(line, []byte("data: [DONE]\n")) {
-			break
+		if content == "" || stopped[index] {
+			return
+		}
+		builder, ok := responses[index]
+		if !ok {
+			builder = &strings.Builder{}
+			responses[index] = builder
+		}
+		if stopPattern != nil {
+			var hit bool
+			if content, hit = truncateAtStop(builder, content); hit {
+				stopped[index] = true
+				if len(stopped) >= expected {
+					cancel()
+				}
+			}
+		}
+		if content != "" {
+			if inReasoning {
+				printLive("\n\n")
+				inReasoning = false
+			}
+			if liveOutput {
+				writeReplyChunk(state, content)
+			}
+			builder.WriteString(content)
+		}
+	}
+
+	for event := range readStreamEvents(body, expected) {
+		if state.Debug {
+			fmt.Printf(msg("\n[DEBUG] 收到事件: %+v\n"), event)
 		}
 
-		var chunk StreamResponse
-		if err := json.Unmarshal(line[6:], &chunk); err != nil {
-			return "", "", fmt.Errorf("解析JSON失败: %w", err)
+		if event.Final {
+			for index, filter := range thinkTags {
+				content, thinking := filter.Flush()
+				emit(index, content, thinking)
+			}
+			// 本地中止导致的读取错误不算失败
+			if event.Err != nil && len(stopped) < expected {
+				finishLine()
+				if errors.Is(event.Err, errStreamBroken) {
+					return buildResult(event), event.Err
+				}
+				return nil, event.Err
+			}
+			result := buildResult(event)
+			if result.Content == "" && len(result.ToolCalls) == 0 {
+				return nil, errors.New(msg("未收到有效回复内容"))
+			}
+			return result, nil
 		}
 
-		if debug {
-			fmt.Printf("\n[DEBUG] 收到数据块: %+v\n", chunk)
+		if firstTokenAt.IsZero() && (event.Content != "" || event.ReasoningContent != "" || len(event.ToolCalls) > 0) {
+			firstTokenAt = time.Now()
 		}
 
-		if requestID == "" && chunk.ID != "" {
-			requestID = chunk.ID
+		emit(event.Index, "", event.ReasoningContent)
+		if event.Index == 0 {
+			logProbs = append(logProbs, event.LogProbs...)
+			toolCalls = mergeToolCallDeltas(toolCalls, event.ToolCalls)
 		}
 
-		if len(chunk.Choices) > 0 {
-			content := chunk.Choices[0].Delta.Content
-			if content != "" {
-				if streamOutput {
-					fmt.Print(content)
-				}
-				fullResponse.WriteString(content)
+		content := event.Content
+		if *dedupChunks && content != "" && content == lastChunk[event.Index] {
+			if state.Debug {
+				fmt.Printf(msg("\n[DEBUG] 丢弃重复数据块: %q\n"), content)
 			}
+			content = ""
+		} else if content != "" {
+			lastChunk[event.Index] = content
+		}
 
-			if chunk.Choices[0].FinishReason == "stop" {
-				break
+		thinking := ""
+		if *stripThink && content != "" {
+			filter, ok := thinkTags[event.Index]
+			if !ok {
+				filter = &thinkFilter{}
+				thinkTags[event.Index] = filter
 			}
+			content, thinking = filter.Feed(content)
+		}
+		emit(event.Index, content, thinking)
+
+		if event.FinishReason != "" {
+			finished++
 		}
 	}
 
-	if fullResponse.Len() == 0 {
-		return "", "", errors.New("未收到有效回复内容")
+	return nil, errors.New(msg("未收到有效回复内容"))
+}
+
+const (
+	ansiReset  = "\033[0m"
+	ansiDim    = "\033[2m"
+	ansiRed    = "\033[31m"
+	ansiGreen  = "\033[32m"
+	ansiYellow = "\033[33m"
+	ansiCyan   = "\033[36m"
+)
+
+// 所有带颜色的输出都经过这里判断：-no-color 或设置了 NO_COLOR(https://no-color.org)时一律不输出颜色
+func colorEnabled() bool {
+	if *noColor || os.Getenv("NO_COLOR") != "" {
+		return false
 	}
+	return readline.IsTerminal(int(os.Stdout.Fd()))
+}
 
-	return fullResponse.String(), requestID, nil
+func colorize(code, text string) string {
+	if !colorEnabled() {
+		return text
+	}
+	return code + text + ansiReset
 }
 
 func printDebugInfo(startTime time.Time, state *ChatState) {
-	fmt.Printf("\n[DEBUG] 本次请求耗时: %.2fs\n", time.Since(startTime).Seconds())
-	fmt.Printf("[DEBUG] 请求ID: %s\n", state.LastRequestID)
-	fmt.Printf("[DEBUG] 当前历史消息数: %d\n", len(state.History))
-	fmt.Printf("[DEBUG] 最后一条历史消息: %+v\n", state.History[len(state.History)-1])
+	fmt.Printf(msg("\n[DEBUG] 本次请求耗时: %.2fs\n"), time.Since(startTime).Seconds())
+	fmt.Printf(msg("[DEBUG] 请求ID: %s\n"), state.LastRequestID)
+	fmt.Printf(msg("[DEBUG] 当前历史消息数: %d\n"), len(state.History))
+	fmt.Printf(msg("[DEBUG] 最后一条历史消息: %+v\n"), state.History[len(state.History)-1])
 }
 
 func printWelcomeMessage(state *ChatState) {
-	fmt.Printf(`
-阿里云百炼对话客户端
-----------------------------------
-当前配置:
-  模型: %s
-  调试模式: %v
-  历史记录文件: %s
-----------------------------------
-命令说明:
-  /help        显示帮助
-  /reset       重置对话
-  /model <模型名> 切换模型
-  /debug       切换调试模式
-  /history     查看命令历史
-  exit         退出程序
-----------------------------------
-`, state.Model, state.Debug, getHistoryFilePath())
+	if *quiet || *bannerMode == "off" {
+		return
+	}
+	fmt.Print(renderBanner(bannerTemplate(), state))
 }
 
-func printHelp() {
-	fmt.Println(`
-交互命令:
-  /help        显示本帮助
-  /reset       清除对话历史
-  /model       显示/切换模型
-  /debug       切换调试信息
-  /history     查看命令历史
-  exit         退出程序
+// 帮助中命令列表之后的固定内容
+const helpFooter = `
+输出过程中按 Ctrl+C 可中断回复(不会退出程序)，随后提示输入补充说明：
+  输入内容后回车：已生成的部分回复和补充说明一起作为上下文重新生成，可再次中断
+  直接回车：保留已生成的部分回复，回到普通输入
 
 单命令模式选项:
   -c string    执行单条命令后退出
+  -f path      读取文件内容作为单条命令(保留引号等原始内容)
   --stream     在单命令模式下启用流式输出
+  -continue    启动交互模式时恢复最近一次保存的对话
+  -json        在单命令模式下以JSON格式输出(出错时附带已收到的部分内容)
+  -quiet       只输出模型回复，适合作为管道过滤器使用
+  -banner off  启动时不显示欢迎信息(或用 -banner custom -banner-file 自定义)
+  -count n     每次请求 n 个回答并逐条编号输出
+  -prefix/-suffix  为每条用户消息添加前缀/后缀
+  -seed n      固定随机种子以获得可复现输出(需服务商支持 seed 参数)
+  -temperature/-top-p  固定采样参数，不随模型切换(可在配置文件 model_profiles 中按模型设置)
+  -lang en|zh  界面语言(默认根据 LANG 环境变量判断)
 
 使用示例:
   # 单命令普通模式
@@ -441,5 +1751,15 @@ func printHelp() {
   ./abls -c "你好" --stream
   
   # 交互模式
-  ./abls`)
+  ./abls
+
+  # 发送初始消息后继续交互
+  ./abls -i "$(cat setup.txt)"
+
+  # 把API密钥保存到系统密钥库，之后从密钥库读取
+  ./abls login
+  ./abls -keychain`
+
+func printHelp() {
+	fmt.Println("\n" + msg("交互命令:") + "\n" + commandHelpText() + msg(helpFooter))
 }
\ No newline at end of file
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func marshalRequestFields(t *testing.T, req StreamRequest) map[string]json.RawMessage {
+	t.Helper()
+	data, err := openAIEncoder{}.Encode(req)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	return fields
+}
+
+var optionalRequestKeys = []string{
+	"n", "enable_thinking", "stream_options", "seed", "presence_penalty", "temperature",
+	"top_p", "logprobs", "top_logprobs", "response_format", "user", "tools",
+}
+
+func TestStreamRequestOmitsUnsetOptions(t *testing.T) {
+	fields := marshalRequestFields(t, StreamRequest{
+		Model:    "qwen-plus",
+		Messages: []Message{{Role: "user", Content: "你好"}},
+		Stream:   true,
+	})
+	for _, key := range optionalRequestKeys {
+		if raw, ok := fields[key]; ok {
+			t.Errorf("未设置的 %s 不应出现在请求中，实际为 %s", key, raw)
+		}
+	}
+	for _, key := range []string{"model", "messages", "stream"} {
+		if _, ok := fields[key]; !ok {
+			t.Errorf("请求缺少 %s", key)
+		}
+	}
+}
+
+func TestStreamRequestKeepsExplicitZero(t *testing.T) {
+	zeroFloat, zeroInt, no := 0.0, 0, false
+	fields := marshalRequestFields(t, StreamRequest{
+		Model:           "qwen-plus",
+		Messages:        []Message{{Role: "user", Content: "你好"}},
+		N:               &zeroInt,
+		EnableThinking:  &no,
+		Seed:            &zeroInt,
+		PresencePenalty: &zeroFloat,
+		Temperature:     &zeroFloat,
+		TopP:            &zeroFloat,
+		LogProbs:        &no,
+		TopLogProbs:     &zeroInt,
+	})
+	want := map[string]string{
+		"n":                "0",
+		"enable_thinking":  "false",
+		"seed":             "0",
+		"presence_penalty": "0",
+		"temperature":      "0",
+		"top_p":            "0",
+		"logprobs":         "false",
+		"top_logprobs":     "0",
+	}
+	for key, value := range want {
+		if got := string(fields[key]); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+}
+
+func TestProcessAIResponseHooks(t *testing.T) {
+	var sent StreamRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&sent); err != nil {
+			t.Errorf("解析请求失败: %v", err)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, sseChunks("原始", "回复"))
+	}))
+	defer server.Close()
+
+	defer func(old string) { *apiEndpoint = old }(*apiEndpoint)
+	*apiEndpoint = server.URL
+
+	state := &ChatState{
+		Model:       "qwen-plus",
+		Client:      server.Client(),
+		isSingleCmd: true,
+		History:     []Message{{Role: "system", Content: "系统"}, {Role: "user", Content: "问题"}},
+		PreSend: func(m Message) Message {
+			m.Content = "[前缀] " + m.Content
+			return m
+		},
+		PostReceive: func(s string) string { return s + "(已处理)" },
+	}
+
+	reply, err := processAIResponse(state, false)
+	if err != nil {
+		t.Fatalf("processAIResponse: %v", err)
+	}
+
+	if last := sent.Messages[len(sent.Messages)-1]; last.Content != "[前缀] 问题" {
+		t.Errorf("发送的用户消息 = %q, want %q", last.Content, "[前缀] 问题")
+	}
+	if state.History[1].Content != "[前缀] 问题" {
+		t.Errorf("历史中的用户消息 = %q, want PreSend 处理后的内容", state.History[1].Content)
+	}
+	if reply != "原始回复(已处理)" {
+		t.Errorf("返回的回复 = %q, want %q", reply, "原始回复(已处理)")
+	}
+	if got := state.History[len(state.History)-1]; got.Role != "assistant" || got.Content != "原始回复(已处理)" {
+		t.Errorf("历史中的回复 = %+v, want PostReceive 处理后的内容", got)
+	}
+}
+
+func TestProcessAIResponseWithoutHooks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, sseChunks("回复"))
+	}))
+	defer server.Close()
+
+	defer func(old string) { *apiEndpoint = old }(*apiEndpoint)
+	*apiEndpoint = server.URL
+
+	state := &ChatState{
+		Model:       "qwen-plus",
+		Client:      server.Client(),
+		isSingleCmd: true,
+		History:     []Message{{Role: "user", Content: "问题"}},
+	}
+	if reply, err := processAIResponse(state, false); err != nil || reply != "回复" {
+		t.Fatalf("processAIResponse = %q, %v; want %q", reply, err, "回复")
+	}
+	if state.History[0].Content != "问题" {
+		t.Errorf("未设置 PreSend 时用户消息被修改为 %q", state.History[0].Content)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 请求耗时直方图的桶上限(秒)
+var latencyBuckets = []float64{0.5, 1, 2, 5, 10, 30, 60, 120}
+
+type latencyHistogram struct {
+	buckets []int // 与 latencyBuckets 对应，不累计
+	count   int
+	sum     float64
+}
+
+// 按模型累计的请求指标，-metrics-addr 时以 Prometheus 文本格式输出
+type requestMetrics struct {
+	mu               sync.Mutex
+	requests         map[string]int
+	errors           map[string]int
+	promptTokens     map[string]int
+	completionTokens map[string]int
+	latency          map[string]*latencyHistogram
+}
+
+var (
+	metrics = &requestMetrics{
+		requests:         map[string]int{},
+		errors:           map[string]int{},
+		promptTokens:     map[string]int{},
+		completionTokens: map[string]int{},
+		latency:          map[string]*latencyHistogram{},
+	}
+	metricsServer *http.Server
+)
+
+func (m *requestMetrics) observe(model string, elapsed time.Duration, result *StreamResult, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[model]++
+	if err != nil {
+		m.errors[model]++
+	}
+	if result != nil && result.Usage != nil {
+		m.promptTokens[model] += result.Usage.PromptTokens
+		m.completionTokens[model] += result.Usage.CompletionTokens
+	}
+
+	h, ok := m.latency[model]
+	if !ok {
+		h = &latencyHistogram{buckets: make([]int, len(latencyBuckets))}
+		m.latency[model] = h
+	}
+	seconds := elapsed.Seconds()
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			h.buckets[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+func (m *requestMetrics) writeTo(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	counters := []struct {
+		name, help string
+		values     map[string]int
+	}{
+		{"abls_requests_total", "Total chat completion requests.", m.requests},
+		{"abls_request_errors_total", "Chat completion requests that failed.", m.errors},
+		{"abls_prompt_tokens_total", "Prompt tokens reported by the API.", m.promptTokens},
+		{"abls_completion_tokens_total", "Completion tokens reported by the API.", m.completionTokens},
+	}
+	for _, c := range counters {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+		for _, model := range sortedKeys(c.values) {
+			fmt.Fprintf(w, "%s{model=%q} %d\n", c.name, model, c.values[model])
+		}
+	}
+
+	const name = "abls_request_duration_seconds"
+	fmt.Fprintf(w, "# HELP %s Chat completion request latency.\n# TYPE %s histogram\n", name, name)
+	for _, model := range sortedKeys(m.latency) {
+		h := m.latency[model]
+		cumulative := 0
+		for i, bound := range latencyBuckets {
+			cumulative += h.buckets[i]
+			fmt.Fprintf(w, "%s_bucket{model=%q,le=\"%g\"} %d\n", name, model, bound, cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket{model=%q,le=\"+Inf\"} %d\n", name, model, h.count)
+		fmt.Fprintf(w, "%s_sum{model=%q} %g\n", name, model, h.sum)
+		fmt.Fprintf(w, "%s_count{model=%q} %d\n", name, model, h.count)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// 先监听再在后台提供服务，地址不可用时能在启动阶段报错
+func startMetricsServer(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		metrics.writeTo(w)
+	})
+	metricsServer = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go metricsServer.Serve(ln)
+	return nil
+}
+
+func closeMetricsServer() {
+	if metricsServer == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	metricsServer.Shutdown(ctx)
+}
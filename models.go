@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 已知模型的元数据
+type ModelInfo struct {
+	Name          string
+	ContextLength int
+	Multimodal    bool
+	Streaming     bool
+}
+
+var knownModels = []ModelInfo{
+	{Name: "qwen-plus", ContextLength: 131072, Streaming: true},
+	{Name: "qwen-max", ContextLength: 32768, Streaming: true},
+	{Name: "qwen-turbo", ContextLength: 1000000, Streaming: true},
+	{Name: "deepseek-r1", ContextLength: 65536, Streaming: true},
+	{Name: "deepseek-v3", ContextLength: 65536, Streaming: true},
+}
+
+func lookupModel(name string) (ModelInfo, bool) {
+	for _, m := range knownModels {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return ModelInfo{}, false
+}
+
+func knownModelNames() []string {
+	names := make([]string, len(knownModels))
+	for i, m := range knownModels {
+		names[i] = m.Name
+	}
+	return names
+}
+
+func printModelInfo(name string) {
+	info, ok := lookupModel(name)
+	if !ok {
+		fmt.Printf(msg("模型: %s\n  上下文长度: unknown\n  多模态: unknown\n  流式输出: unknown\n"), name)
+		return
+	}
+
+	fmt.Printf(msg("模型: %s\n  上下文长度: %d tokens\n  多模态: %s\n  流式输出: %s\n"),
+		info.Name, info.ContextLength, yesNo(info.Multimodal), yesNo(info.Streaming))
+}
+
+func yesNo(v bool) string {
+	if v {
+		return msg("支持")
+	}
+	return msg("不支持")
+}
+
+// 模型不存在时推荐的替代模型，同系列(名称第一段相同)的排在前面
+func suggestModels(model string) []string {
+	family, _, _ := strings.Cut(model, "-")
+	var same, other []string
+	for _, name := range knownModelNames() {
+		switch {
+		case name == model:
+		case strings.HasPrefix(name, family+"-"):
+			same = append(same, name)
+		default:
+			other = append(other, name)
+		}
+	}
+	return append(same, other...)
+}
+
+func availableModelsText() string {
+	return strings.Join(knownModelNames(), ", ")
+}
+
+// 配置文件 model_aliases 中的别名，可按环境使用不同的配置文件(-config)指向不同模型
+func resolveModelAlias(name string) (string, bool) {
+	model, ok := appConfig.ModelAliases[name]
+	return model, ok && model != ""
+}
+
+func modelAliasesText() string {
+	var pairs []string
+	for _, alias := range sortedKeys(appConfig.ModelAliases) {
+		pairs = append(pairs, alias+"="+appConfig.ModelAliases[alias])
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// 消息以 "@模型名:" 开头时返回该模型和去掉前缀的消息，模型名可以是别名。
+// 不是已知模型或别名时按普通消息处理
+func splitModelPrefix(input string) (model, rest string, ok bool) {
+	name, rest, found := strings.Cut(strings.TrimPrefix(input, "@"), ":")
+	rest = strings.TrimSpace(rest)
+	if !strings.HasPrefix(input, "@") || !found || name == "" || rest == "" || strings.ContainsAny(name, " \t") {
+		return "", input, false
+	}
+	if resolved, isAlias := resolveModelAlias(name); isAlias {
+		return resolved, rest, true
+	}
+	if _, known := lookupModel(name); known {
+		return name, rest, true
+	}
+	return "", input, false
+}
+
+// 临时切换模型回答一轮，返回的函数恢复原模型及采样参数
+func useModelForTurn(state *ChatState, model string) (restore func()) {
+	savedModel, savedAlias := state.Model, state.ModelAlias
+	savedTemperature, savedTopP := state.Temperature, state.TopP
+
+	state.Model, state.ModelAlias = model, ""
+	applyModelProfile(state)
+	return func() {
+		state.Model, state.ModelAlias = savedModel, savedAlias
+		state.Temperature, state.TopP = savedTemperature, savedTopP
+	}
+}
+
+// 通过别名选择时显示为 "别名 (模型)"
+func modelLabel(state *ChatState) string {
+	if state.ModelAlias != "" {
+		return state.ModelAlias + " (" + state.Model + ")"
+	}
+	return state.Model
+}
+
+// 用另一个模型在全新上下文中回答上一个问题，不修改对话历史
+func handleCompare(input string, state *ChatState) {
+	parts := strings.Fields(input)
+	if len(parts) < 2 {
+		fmt.Println(msg("用法: /compare <模型名>"))
+		return
+	}
+	model := parts[1]
+	if _, ok := lookupModel(model); !ok {
+		fmt.Println(msg("错误：不支持的模型"))
+		return
+	}
+
+	userIdx := -1
+	for i := len(state.History) - 1; i >= 0; i-- {
+		if state.History[i].Role == "user" {
+			userIdx = i
+			break
+		}
+	}
+	if userIdx < 0 {
+		fmt.Println(msg("错误：还没有可对比的用户消息"))
+		return
+	}
+
+	if userIdx+1 < len(state.History) && state.History[userIdx+1].Role == "assistant" {
+		fmt.Printf(msg("=== %s (当前) ===\n"), state.Model)
+		printReply(state.History[userIdx+1].Content)
+	}
+
+	messages := []Message{
+		{Role: "system", Content: currentSystemPrompt(state)},
+		state.History[userIdx],
+	}
+	fmt.Printf("=== %s ===\n", model)
+	result, err := streamChatCompletion(state, model, messages, true)
+	finishLine()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, msg("错误: %v\n"), err)
+		return
+	}
+	if len(result.Choices) > 1 {
+		printChoices(result.Choices)
+	}
+}
+
+// 模糊匹配：关键字的字符按顺序出现在名称中即可，忽略大小写
+func fuzzyMatch(name, query string) bool {
+	name, query = strings.ToLower(name), strings.ToLower(query)
+	for _, r := range query {
+		i := strings.IndexRune(name, r)
+		if i < 0 {
+			return false
+		}
+		name = name[i+len(string(r)):]
+	}
+	return true
+}
+
+// 交互式选择模型，可输入序号或名称片段逐步缩小范围
+func pickModel(input string, state *ChatState) {
+	if state.rl == nil {
+		fmt.Println(msg("错误：/pick 仅在交互模式下可用"))
+		return
+	}
+
+	query := strings.TrimSpace(strings.TrimPrefix(input, "/pick"))
+	candidates := knownModelNames()
+	defer updatePrompt(state)
+	for {
+		var matched []string
+		for _, name := range candidates {
+			if fuzzyMatch(name, query) {
+				matched = append(matched, name)
+			}
+		}
+
+		switch len(matched) {
+		case 0:
+			fmt.Printf(msg("没有匹配 %q 的模型\n"), query)
+			matched = candidates
+		case 1:
+			switchModel(state, matched[0])
+			return
+		}
+
+		candidates = matched
+		for i, name := range candidates {
+			marker := " "
+			if name == state.Model {
+				marker = "*"
+			}
+			fmt.Printf("  %s%2d. %s\n", marker, i+1, name)
+		}
+
+		state.rl.SetPrompt(msg("选择模型(序号或名称片段，回车取消): "))
+		answer, err := state.rl.Readline()
+		answer = strings.TrimSpace(answer)
+		if err != nil || answer == "" {
+			return
+		}
+		if n, err := strconv.Atoi(answer); err == nil {
+			if n < 1 || n > len(candidates) {
+				fmt.Println(msg("错误：序号超出范围"))
+				query = ""
+				continue
+			}
+			switchModel(state, candidates[n-1])
+			return
+		}
+		query = answer
+	}
+}
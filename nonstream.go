@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// 非流式响应，回答内容在 message 而不是 delta 中
+type ChatResponse struct {
+	ID      string       `json:"id"`
+	Model   string       `json:"model"`
+	Choices []chatChoice `json:"choices"`
+	Usage   *Usage       `json:"usage,omitempty"`
+}
+
+type chatChoice struct {
+	Index   int `json:"index"`
+	Message struct {
+		Content          string     `json:"content"`
+		ReasoningContent string     `json:"reasoning_content,omitempty"`
+		ToolCalls        []ToolCall `json:"tool_calls,omitempty"`
+	} `json:"message"`
+	FinishReason string `json:"finish_reason,omitempty"`
+	LogProbs     *struct {
+		Content []TokenLogProb `json:"content"`
+	} `json:"logprobs,omitempty"`
+}
+
+// 部分兼容接口不支持 "stream": true，会直接返回 400
+func streamUnsupported(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		return false
+	}
+	return strings.Contains(strings.ToLower(apiErr.Body), "stream")
+}
+
+func sendNonStreamRequest(state *ChatState, model string, messages []Message, streamOutput bool) (*StreamResult, error) {
+	payload := buildPayload(state, model, messages)
+
+	ctx, done := cancellableRequest(state)
+	defer done()
+
+	sentAt := time.Now()
+	resp, body, err := postChatRequest(ctx, state, payload)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if err != nil {
+		if resp != nil {
+			return &StreamResult{StatusCode: resp.StatusCode}, err
+		}
+		return nil, cancelledErr(ctx, err)
+	}
+
+	var chat ChatResponse
+	if *requestFormat == "anthropic" {
+		chat, err = decodeAnthropicResponse(body)
+	} else {
+		err = json.NewDecoder(body).Decode(&chat)
+	}
+	if err != nil {
+		if errors.Is(context.Cause(ctx), errStreamCancelled) {
+			return nil, errStreamCancelled
+		}
+		return nil, fmt.Errorf(msg("解析JSON失败: %w"), err)
+	}
+	if state.Debug {
+		fmt.Printf(msg("\n[DEBUG] 收到响应: %+v\n"), chat)
+	}
+
+	result := &StreamResult{
+		RequestID:  chat.ID,
+		Usage:      chat.Usage,
+		Done:       true,
+		TTFT:       time.Since(sentAt),
+		StatusCode: resp.StatusCode,
+	}
+	for _, choice := range chat.Choices {
+		if choice.Index != len(result.Choices) {
+			continue
+		}
+		content, thinking := choice.Message.Content, ""
+		if *stripThink {
+			content, thinking = stripThinkTags(content)
+		}
+		result.Choices = append(result.Choices, content)
+		if choice.Index == 0 {
+			result.Content = content
+			result.Reasoning = choice.Message.ReasoningContent + thinking
+			result.ToolCalls = choice.Message.ToolCalls
+			if choice.LogProbs != nil {
+				result.LogProbs = choice.LogProbs.Content
+			}
+		}
+	}
+	if result.Content == "" && len(result.ToolCalls) == 0 {
+		return nil, errors.New(msg("未收到有效回复内容"))
+	}
+
+	// 调用方按流式处理，不会再输出回复，这里一次性补上
+	if streamOutput && len(result.Choices) == 1 {
+		if result.Reasoning != "" && shouldShowReasoning(state) {
+			printLive(colorize(ansiDim, msg("[思考] ")+result.Reasoning) + "\n\n")
+		}
+		writeReplyChunk(state, result.Content)
+	}
+	return result, nil
+}
+
+// 记住本次会话中服务端不支持流式请求，避免每次都先收到 400
+func fallbackToNonStream(state *ChatState, err error) {
+	state.streamUnsupported = true
+	if !*quiet {
+		fmt.Fprintf(os.Stderr, msg("\n[服务端不支持流式请求，改用非流式请求] %v\n"), err)
+	}
+}
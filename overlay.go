@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// /overlay push <提示词>: 在系统提示词之上叠加任务相关的提示词，发送时作为额外的系统消息
+// 紧跟在基础系统提示词之后。基础提示词保持不变，任务变化时只需替换叠加层
+func handleOverlayCommand(input string, state *ChatState) {
+	parts := strings.Fields(input)
+	if len(parts) < 2 {
+		fmt.Println(msg("用法: /overlay push <提示词> | /overlay pop | /overlay list | /overlay clear"))
+		return
+	}
+
+	switch parts[1] {
+	case "push":
+		_, text, _ := strings.Cut(input, "push")
+		text = strings.TrimSpace(text)
+		if text == "" {
+			fmt.Println(msg("用法: /overlay push <提示词>"))
+			return
+		}
+		state.Overlays = append(state.Overlays, text)
+		fmt.Printf(msg("已叠加提示词，当前共 %d 层\n"), len(state.Overlays))
+	case "pop":
+		if len(state.Overlays) == 0 {
+			fmt.Println(msg("没有叠加的提示词"))
+			return
+		}
+		top := state.Overlays[len(state.Overlays)-1]
+		state.Overlays = state.Overlays[:len(state.Overlays)-1]
+		fmt.Printf(msg("已移除: %s\n"), previewText(top, 60))
+	case "list":
+		listOverlays(state)
+	case "clear":
+		state.Overlays = nil
+		fmt.Println(msg("已清除全部叠加的提示词"))
+	default:
+		fmt.Println(msg("错误：未知的子命令"), parts[1])
+	}
+}
+
+func listOverlays(state *ChatState) {
+	if len(state.Overlays) == 0 {
+		fmt.Println(msg("没有叠加的提示词"))
+		return
+	}
+	fmt.Println(msg("叠加的提示词(由下到上):"))
+	for i, overlay := range state.Overlays {
+		fmt.Printf("  %d. %s\n", i+1, previewText(overlay, 60))
+	}
+}
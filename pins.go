@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 超出 -max-messages 时从最早的消息开始裁剪，系统提示词和置顶消息保留
+func trimHistory(state *ChatState) {
+	if *maxMessages <= 0 || len(state.History) <= *maxMessages {
+		return
+	}
+
+	kept, pinned := trimMessages(state.History, state.Pinned)
+	if state.Debug {
+		fmt.Printf(msg("[DEBUG] 裁剪历史消息: %d -> %d\n"), len(state.History), len(kept))
+	}
+	state.History = kept
+	state.Pinned = pinned
+}
+
+// 按 -max-messages 裁剪后的消息及新的置顶序号，不修改传入的历史
+func trimMessages(history []Message, pinnedIn map[int]bool) ([]Message, map[int]bool) {
+	limit := *maxMessages
+	if limit <= 0 || len(history) <= limit {
+		return history, pinnedIn
+	}
+
+	excess := len(history) - limit
+	kept := make([]Message, 0, limit)
+	pinned := map[int]bool{}
+	for i, msg := range history {
+		if i > 0 && excess > 0 && !pinnedIn[i] {
+			excess--
+			continue
+		}
+		if pinnedIn[i] {
+			pinned[len(kept)] = true
+		}
+		kept = append(kept, msg)
+	}
+	return kept, pinned
+}
+
+func handlePinCommand(input string, state *ChatState) {
+	parts := strings.Fields(input)
+	if len(parts) < 2 {
+		listMessages(state)
+		return
+	}
+
+	index, err := strconv.Atoi(parts[1])
+	if err != nil || index < 1 || index >= len(state.History) {
+		fmt.Printf(msg("错误：无效的消息序号，可用范围 1-%d\n"), len(state.History)-1)
+		return
+	}
+
+	if parts[0] == "/pin" {
+		state.Pinned[index] = true
+		fmt.Printf(msg("已置顶消息 %d\n"), index)
+	} else {
+		delete(state.Pinned, index)
+		fmt.Printf(msg("已取消置顶消息 %d\n"), index)
+	}
+}
+
+func showPins(state *ChatState) {
+	if len(state.Pinned) == 0 {
+		fmt.Println(msg("暂无置顶消息"))
+		return
+	}
+
+	fmt.Println(msg("置顶消息:"))
+	for i, msg := range state.History {
+		if state.Pinned[i] {
+			fmt.Printf("%4d: [%s] %s\n", i, msg.Role, previewText(msg.Content, 60))
+		}
+	}
+}
+
+func listMessages(state *ChatState) {
+	fmt.Println(msg("对话消息(序号用于 /pin <序号>):"))
+	for i, msg := range state.History {
+		mark := " "
+		if i == 0 || state.Pinned[i] {
+			mark = "*"
+		}
+		fmt.Printf("%s%3d: [%s] %s\n", mark, i, msg.Role, previewText(msg.Content, 60))
+	}
+}
+
+func previewText(text string, limit int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit]) + "..."
+}
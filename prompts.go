@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const defaultSystemPrompt = "You are a helpful assistant."
+
+func getPromptsDir() string {
+	return filepath.Join(ablsDir(), "prompts")
+}
+
+// 设置系统提示词，/reset 后继续生效
+func setSystemPrompt(state *ChatState, content string) {
+	state.SystemPrompt = content
+	state.Role = ""
+	updatePrompt(state)
+	if len(state.History) > 0 && state.History[0].Role == "system" {
+		state.History[0].Content = content
+		return
+	}
+	state.History = append([]Message{{Role: "system", Content: content}}, state.History...)
+}
+
+func currentSystemPrompt(state *ChatState) string {
+	if len(state.History) > 0 && state.History[0].Role == "system" {
+		return state.History[0].Content
+	}
+	return ""
+}
+
+func validTemplateName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, `/\`) && name != "." && name != ".."
+}
+
+func handlePromptCommand(input string, state *ChatState) {
+	parts := strings.Fields(input)
+	if len(parts) < 2 {
+		fmt.Println(msg("用法: /prompt save <名称> | /prompt use <名称> | /prompt list"))
+		return
+	}
+
+	switch parts[1] {
+	case "list":
+		listPromptTemplates()
+	case "save", "use":
+		if len(parts) < 3 || !validTemplateName(parts[2]) {
+			fmt.Printf(msg("用法: /prompt %s <名称>\n"), parts[1])
+			return
+		}
+		if parts[1] == "save" {
+			savePromptTemplate(parts[2], state)
+		} else {
+			usePromptTemplate(parts[2], state)
+		}
+	default:
+		fmt.Println(msg("错误：未知的子命令"), parts[1])
+	}
+}
+
+func savePromptTemplate(name string, state *ChatState) {
+	content := currentSystemPrompt(state)
+	if content == "" {
+		fmt.Println(msg("错误：当前没有系统提示词"))
+		return
+	}
+
+	dir := getPromptsDir()
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		fmt.Fprintf(os.Stderr, msg("创建模板目录失败: %v\n"), err)
+		return
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+".txt"), []byte(content), 0o600); err != nil {
+		fmt.Fprintf(os.Stderr, msg("保存模板失败: %v\n"), err)
+		return
+	}
+	fmt.Printf(msg("已保存提示词模板: %s\n"), name)
+}
+
+func usePromptTemplate(name string, state *ChatState) {
+	data, err := os.ReadFile(filepath.Join(getPromptsDir(), name+".txt"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			fmt.Printf(msg("错误：模板 %s 不存在\n"), name)
+		} else {
+			fmt.Fprintf(os.Stderr, msg("读取模板失败: %v\n"), err)
+		}
+		return
+	}
+
+	setSystemPrompt(state, strings.TrimSpace(string(data)))
+	fmt.Printf(msg("已使用提示词模板: %s\n"), name)
+}
+
+func listPromptTemplates() {
+	names := listTemplateNames(getPromptsDir(), ".txt")
+	if len(names) == 0 {
+		fmt.Println(msg("暂无提示词模板"))
+		return
+	}
+
+	fmt.Println(msg("提示词模板:"))
+	for _, name := range names {
+		fmt.Printf("  %s\n", name)
+	}
+}
+
+func listTemplateNames(dir, ext string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ext) {
+			names = append(names, strings.TrimSuffix(entry.Name(), ext))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// /prompt-preview [消息]: 按发送时的处理顺序组装 messages 并输出，不发送请求
+func previewPrompt(input string, state *ChatState) {
+	history := append([]Message(nil), state.History...)
+	if text := strings.TrimSpace(strings.TrimPrefix(input, "/prompt-preview")); text != "" {
+		history = append(history, Message{Role: "user", Content: assembleUserMessage(state, expandAlias(state, text))})
+	}
+
+	history, _ = trimMessages(history, state.Pinned)
+	if last := len(history) - 1; state.PreSend != nil && last >= 0 && history[last].Role == "user" {
+		history[last] = state.PreSend(history[last])
+	}
+
+	var b strings.Builder
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	messages := assembleMessages(history, state.Overlays, state.ContextDocs)
+	if state.AutoLang {
+		messages = withLanguageHint(messages)
+	}
+	// 与编码请求时一样合并相邻同角色消息并映射角色名
+	messages = mapRoles(mergeRoles(messages))
+	if err := encoder.Encode(withoutTimestamps(messages)); err != nil {
+		fmt.Fprintf(os.Stderr, msg("JSON编码失败: %v\n"), err)
+		return
+	}
+	fmt.Print(b.String())
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// 交互模式下按 Ctrl+C 中断正在输出的回复
+var errStreamCancelled error = streamCancelledError{}
+
+type streamCancelledError struct{}
+
+func (streamCancelledError) Error() string { return msg("已中断输出") }
+
+// 创建可由 Ctrl+C 取消的请求 context，请求结束后调用返回的函数注销
+func cancellableRequest(state *ChatState) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	state.streamMu.Lock()
+	state.cancelStream = cancel
+	state.streamMu.Unlock()
+
+	return ctx, func() {
+		state.streamMu.Lock()
+		state.cancelStream = nil
+		state.streamMu.Unlock()
+		cancel(nil)
+	}
+}
+
+// 有请求进行中时取消它并返回 true
+func interruptStream(state *ChatState) bool {
+	state.streamMu.Lock()
+	defer state.streamMu.Unlock()
+	if state.cancelStream == nil {
+		return false
+	}
+	state.cancelStream(errStreamCancelled)
+	return true
+}
+
+// 因 Ctrl+C 而失败的请求统一返回 errStreamCancelled，避免被当作断流续传
+func cancelledErr(ctx context.Context, err error) error {
+	if err != nil && errors.Is(context.Cause(ctx), errStreamCancelled) {
+		return errStreamCancelled
+	}
+	return err
+}
+
+// 中断后可输入补充说明：已生成的部分回复和补充说明一起作为上下文重新生成。
+// 直接回车则保留部分回复并结束
+func refineAfterCancel(state *ChatState) {
+	for {
+		partial := ""
+		if state.LastResult != nil {
+			partial = state.LastResult.Content
+		}
+		if partial != "" {
+			state.History = append(state.History, newMessage("assistant", partial))
+		}
+
+		state.rl.SetPrompt(msg("补充说明(直接回车保留已生成内容): "))
+		followUp, err := state.rl.Readline()
+		updatePrompt(state)
+		followUp = strings.TrimSpace(followUp)
+		if err != nil || followUp == "" {
+			if partial != "" {
+				fmt.Println(msg("已保留部分回复"))
+			}
+			return
+		}
+
+		recordCommand(state, followUp)
+		state.History = append(state.History, newMessage("user", prepareUserMessage(state, followUp)))
+		_, err = processAIResponse(state, true)
+		if errors.Is(err, errStreamCancelled) {
+			discardStreamDisplay()
+		}
+		finishLine()
+		if err == nil {
+			return
+		}
+		if !errors.Is(err, errStreamCancelled) {
+			fmt.Fprintf(os.Stderr, msg("错误: %v\n"), err)
+			return
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode"
+
+	"readline"
+)
+
+// 输出到终端且开启 -wrap 时返回可用宽度，否则返回 0
+func wrapWidth() int {
+	if !*wrapOutput || !readline.IsTerminal(int(os.Stdout.Fd())) {
+		return 0
+	}
+	return readline.GetScreenWidth()
+}
+
+func printReply(text string) {
+	if *prettyJSON {
+		text = prettyPrintJSON(text)
+	}
+	if width := wrapWidth(); width > 0 {
+		text = wrapText(text, width)
+	}
+	if *pageOutput && readline.IsTerminal(int(os.Stdout.Fd())) && runPager(text+"\n") == nil {
+		return
+	}
+	fmt.Println(text)
+}
+
+// -trim-reply: 去掉回复首尾的空白。开头只去掉空行和少量空格，
+// 第一行缩进四个空格或制表符时可能是缩进代码块，保留缩进
+func trimReply(text string) string {
+	text = strings.TrimRightFunc(text, unicode.IsSpace)
+	for {
+		line, rest, ok := strings.Cut(text, "\n")
+		if !ok || strings.TrimSpace(line) != "" {
+			break
+		}
+		text = rest
+	}
+	if !strings.HasPrefix(text, "    ") && !strings.HasPrefix(text, "\t") {
+		text = strings.TrimLeft(text, " ")
+	}
+	return text
+}
+
+// 用 $PAGER(默认 less)显示内容，找不到分页程序时返回错误由调用方直接输出
+func runPager(text string) error {
+	args := strings.Fields(os.Getenv("PAGER"))
+	if len(args) == 0 {
+		args = []string{"less"}
+	}
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(path, args[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	// 内容不足一屏时 less 直接输出并退出，保留颜色
+	if os.Getenv("LESS") == "" {
+		cmd.Env = append(os.Environ(), "LESS=FRX")
+	}
+	return cmd.Run()
+}
+
+// 整段回复或 ```json 代码块是合法 JSON 时重新缩进，其余内容不变
+func prettyPrintJSON(text string) string {
+	if indented, ok := indentJSON(text); ok {
+		return indented
+	}
+
+	lines := strings.Split(text, "\n")
+	var out []string
+	for i := 0; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) != "```json" {
+			out = append(out, lines[i])
+			continue
+		}
+		end := i + 1
+		for end < len(lines) && strings.TrimSpace(lines[end]) != "```" {
+			end++
+		}
+		if end == len(lines) {
+			out = append(out, lines[i:]...)
+			break
+		}
+		if indented, ok := indentJSON(strings.Join(lines[i+1:end], "\n")); ok {
+			out = append(out, lines[i], indented, lines[end])
+		} else {
+			out = append(out, lines[i:end+1]...)
+		}
+		i = end
+	}
+	return strings.Join(out, "\n")
+}
+
+func indentJSON(text string) (string, bool) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "{") && !strings.HasPrefix(text, "[") {
+		return "", false
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(text), "", "  "); err != nil {
+		return "", false
+	}
+	return buf.String(), true
+}
+
+// 按显示宽度折行，代码块和缩进的预格式化行保持原样
+func wrapText(text string, width int) string {
+	var out []string
+	inFence := false
+
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			out = append(out, line)
+			continue
+		}
+		if inFence || isPreformatted(line) || displayWidth(line) <= width {
+			out = append(out, line)
+			continue
+		}
+		out = append(out, wrapLine(line, width)...)
+	}
+	return strings.Join(out, "\n")
+}
+
+func isPreformatted(line string) bool {
+	return strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "    ")
+}
+
+func wrapLine(line string, width int) []string {
+	var (
+		lines []string
+		cur   strings.Builder
+		curW  int
+	)
+	flush := func() {
+		lines = append(lines, strings.TrimRight(cur.String(), " "))
+		cur.Reset()
+		curW = 0
+	}
+
+	for _, tok := range splitWrapTokens(line) {
+		w := displayWidth(tok)
+		isSpace := strings.TrimSpace(tok) == ""
+
+		if curW > 0 && curW+w > width {
+			flush()
+		}
+		if isSpace && curW == 0 && len(lines) > 0 {
+			continue
+		}
+
+		// 超长单词按字符硬折行
+		if w > width {
+			for _, r := range tok {
+				rw := runeWidth(r)
+				if curW > 0 && curW+rw > width {
+					flush()
+				}
+				cur.WriteRune(r)
+				curW += rw
+			}
+			continue
+		}
+
+		cur.WriteString(tok)
+		curW += w
+	}
+	if cur.Len() > 0 {
+		flush()
+	}
+	return lines
+}
+
+// 空白和西文单词各成一段，宽字符(中日韩)逐字可断
+func splitWrapTokens(line string) []string {
+	var (
+		tokens []string
+		cur    strings.Builder
+		space  bool
+	)
+	emit := func() {
+		if cur.Len() > 0 {
+			tokens = append(tokens, cur.String())
+			cur.Reset()
+		}
+	}
+
+	for _, r := range line {
+		switch {
+		case runeWidth(r) == 2:
+			emit()
+			tokens = append(tokens, string(r))
+		case unicode.IsSpace(r) != space:
+			emit()
+			space = !space
+			cur.WriteRune(r)
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	emit()
+	return tokens
+}
+
+func displayWidth(s string) int {
+	w := 0
+	for _, r := range s {
+		w += runeWidth(r)
+	}
+	return w
+}
+
+func runeWidth(r rune) int {
+	switch {
+	case unicode.Is(unicode.Mn, r):
+		return 0
+	case r >= 0x1100 && r <= 0x115F,
+		r >= 0x2E80 && r <= 0xA4CF,
+		r >= 0xAC00 && r <= 0xD7A3,
+		r >= 0xF900 && r <= 0xFAFF,
+		r >= 0xFE30 && r <= 0xFE4F,
+		r >= 0xFF00 && r <= 0xFF60,
+		r >= 0xFFE0 && r <= 0xFFE6,
+		r >= 0x1F300 && r <= 0x1F64F,
+		r >= 0x1F900 && r <= 0x1F9FF,
+		r >= 0x20000 && r <= 0x3FFFD:
+		return 2
+	}
+	return 1
+}
+
+// 标准输出是否停在行中间(流式输出尚未换行)，出错或中断时据此补上换行
+var midLine bool
+
+// 实时输出到标准输出并记录是否停在行中间
+func printLive(s string) {
+	if s == "" {
+		return
+	}
+	fmt.Print(s)
+	midLine = !strings.HasSuffix(s, "\n")
+}
+
+// 回复内容输出到全部流式输出目标，只有显示在终端上时才影响行状态
+func writeStream(s string) {
+	if s == "" {
+		return
+	}
+	io.WriteString(streamOut, s)
+	if streamToStdout() {
+		midLine = !strings.HasSuffix(s, "\n")
+	}
+}
+
+// 停在行中间时补一个换行，保证后续输出从新行开始
+func finishLine() {
+	waitStreamDisplay()
+	if midLine {
+		fmt.Println()
+		midLine = false
+	}
+}
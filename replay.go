@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// -record 文件中的一行：一次读取到的原始 SSE 数据及距上一次读取的间隔。
+// 一次读取可能在多字节字符中间截断，Data 按字节保存(JSON 中为 base64)，回放时原样还原
+type recordedChunk struct {
+	DelayMS int64  `json:"delay_ms"`
+	Data    []byte `json:"data"`
+}
+
+// 把读取到的原始响应数据连同时间间隔写入 -record 文件
+type recordingReader struct {
+	r    io.Reader
+	enc  *json.Encoder
+	last time.Time
+}
+
+func newRecordingReader(r io.Reader, path string) (*recordingReader, *os.File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, nil, fmt.Errorf(msg("打开 -record 文件失败: %w"), err)
+	}
+	return &recordingReader{r: r, enc: json.NewEncoder(f), last: time.Now()}, f, nil
+}
+
+func (rr *recordingReader) Read(p []byte) (int, error) {
+	n, err := rr.r.Read(p)
+	if n > 0 && rr.enc != nil {
+		now := time.Now()
+		// 写入失败不影响本次响应，只停止记录
+		if encErr := rr.enc.Encode(recordedChunk{DelayMS: now.Sub(rr.last).Milliseconds(), Data: p[:n]}); encErr != nil {
+			fmt.Fprintf(os.Stderr, msg("警告：写入 -record 文件失败，停止记录: %v\n"), encErr)
+			rr.enc = nil
+		}
+		rr.last = now
+	}
+	return n, err
+}
+
+// 读取 -replay 文件作为响应体。文件可以是 -record 的记录，也可以是原始 SSE 数据；
+// 开启 -replay-timing 时按记录中的间隔输出
+func openReplay(ctx context.Context, path string) (io.Reader, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf(msg("读取 -replay 文件失败: %w"), err)
+	}
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return bytes.NewReader(data), nil
+	}
+
+	var chunks []recordedChunk
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for scanner.Scan() {
+		var chunk recordedChunk
+		if err := json.Unmarshal(scanner.Bytes(), &chunk); err != nil {
+			return nil, fmt.Errorf(msg("解析 -replay 文件失败: %w"), err)
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		for _, chunk := range chunks {
+			if *replayTiming && chunk.DelayMS > 0 {
+				select {
+				case <-time.After(time.Duration(chunk.DelayMS) * time.Millisecond):
+				case <-ctx.Done():
+					pw.CloseWithError(context.Cause(ctx))
+					return
+				}
+			}
+			if _, err := pw.Write(chunk.Data); err != nil {
+				return
+			}
+		}
+		pw.Close()
+	}()
+	return pr, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func getRolesDir() string {
+	return filepath.Join(ablsDir(), "roles")
+}
+
+func handleRoleCommand(input string, state *ChatState) {
+	parts := strings.Fields(input)
+	if len(parts) < 2 {
+		if state.Role == "" {
+			fmt.Println(msg("当前未使用角色"))
+		} else {
+			fmt.Printf(msg("当前角色: %s\n"), state.Role)
+		}
+		fmt.Println(msg("用法: /role <名称> | /role list"))
+		return
+	}
+
+	if parts[1] == "list" {
+		listRoles()
+		return
+	}
+	if !validTemplateName(parts[1]) {
+		fmt.Println(msg("错误：无效的角色名称"))
+		return
+	}
+	if err := useRole(state, parts[1]); err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf(msg("已切换角色: %s\n"), state.Role)
+}
+
+// 读取角色文件作为系统提示词，/reset 后继续生效
+func useRole(state *ChatState, name string) error {
+	data, err := os.ReadFile(filepath.Join(getRolesDir(), name+".md"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf(msg("错误：角色 %s 不存在，可用角色: %s"), name, availableRolesText())
+		}
+		return fmt.Errorf(msg("读取角色失败: %w"), err)
+	}
+
+	setSystemPrompt(state, strings.TrimSpace(string(data)))
+	state.Role = name
+	updatePrompt(state)
+	return nil
+}
+
+func availableRolesText() string {
+	names := listTemplateNames(getRolesDir(), ".md")
+	if len(names) == 0 {
+		return fmt.Sprintf(msg("无(角色文件放在 %s)"), getRolesDir())
+	}
+	return strings.Join(names, ", ")
+}
+
+func listRoles() {
+	names := listTemplateNames(getRolesDir(), ".md")
+	if len(names) == 0 {
+		fmt.Printf(msg("暂无角色，可在 %s 中添加 <名称>.md\n"), getRolesDir())
+		return
+	}
+
+	fmt.Println(msg("角色:"))
+	for _, name := range names {
+		fmt.Printf("  %s\n", name)
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// -serve: 作为本地网关运行，其他程序 POST /v1/chat 即可通过本进程配置的服务商和密钥对话。
+// 每个请求使用独立的对话状态，不读写交互模式的历史
+type serveRequest struct {
+	Messages []Message `json:"messages"`
+	Model    string    `json:"model,omitempty"` // 可以是 model_aliases 中的别名，为空时使用 -model
+	Stream   bool      `json:"stream"`
+}
+
+// 非流式请求的响应体，也是流式响应最后一个事件的内容
+type serveResponse struct {
+	Model     string     `json:"model"`
+	Content   string     `json:"content"`
+	Reasoning string     `json:"reasoning,omitempty"`
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	RequestID string     `json:"request_id,omitempty"`
+	Usage     *Usage     `json:"usage,omitempty"`
+	Done      bool       `json:"done"`
+}
+
+const maxServeRequestBytes = 8 << 20
+
+func runServer(base *ChatState, addr string) error {
+	if *serveToken == "" {
+		return errors.New(msg("-serve 需要用 -serve-token 设置访问令牌"))
+	}
+	if !*servePublic && !isLoopbackAddr(addr) {
+		return fmt.Errorf(msg("%s 不是本机地址，确需对外提供服务请加 -serve-public"), addr)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/chat", func(w http.ResponseWriter, r *http.Request) {
+		if !validServeToken(r) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeServeError(w, http.StatusUnauthorized, errors.New(msg("访问令牌无效")))
+			return
+		}
+		serveChat(base, w, r)
+	})
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	if !*quiet {
+		fmt.Printf(msg("已在 %s 提供 POST /v1/chat，默认模型 %s\n"), addr, modelLabel(base))
+	}
+	return server.ListenAndServe()
+}
+
+// 主机部分为空(如 ":8080")时监听所有网卡，不算本机地址
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func validServeToken(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(*serveToken)) == 1
+}
+
+func serveChat(base *ChatState, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeServeError(w, http.StatusMethodNotAllowed, errors.New(msg("只支持 POST")))
+		return
+	}
+
+	var req serveRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxServeRequestBytes)).Decode(&req); err != nil {
+		writeServeError(w, http.StatusBadRequest, fmt.Errorf(msg("解析请求失败: %w"), err))
+		return
+	}
+	if err := validServeMessages(req.Messages); err != nil {
+		writeServeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	state := serveState(base, req.Model)
+	// 客户端断开时取消上游请求
+	stop := context.AfterFunc(r.Context(), func() { interruptStream(state) })
+	defer stop()
+
+	if !req.Stream {
+		result, err := streamChatCompletion(state, state.Model, req.Messages, false)
+		if err != nil {
+			writeServeError(w, serveErrorStatus(err), err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(newServeResponse(state, result))
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	send := func(event any) {
+		data, _ := json.Marshal(event)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	state.serveOutput = func(content string) {
+		send(map[string]string{"content": content})
+	}
+
+	result, err := streamChatCompletion(state, state.Model, req.Messages, true)
+	if err != nil {
+		send(map[string]string{"error": err.Error()})
+	} else {
+		send(newServeResponse(state, result))
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
+// 请求使用的对话状态：复制启动参数确定的采样设置，请求指定模型时按该模型的配置重新计算
+func serveState(base *ChatState, model string) *ChatState {
+	state := &ChatState{
+		Model:          base.Model,
+		ModelAlias:     base.ModelAlias,
+		Client:         base.Client,
+		EnableThinking: base.EnableThinking,
+		Seed:           base.Seed,
+		JSONMode:       base.JSONMode,
+
+		PresencePenalty: base.PresencePenalty,
+		Temperature:     base.Temperature,
+		TopP:            base.TopP,
+	}
+	if model != "" && model != base.Model {
+		state.Model, state.ModelAlias = model, ""
+		if resolved, ok := resolveModelAlias(model); ok {
+			state.Model, state.ModelAlias = resolved, model
+		}
+		applyModelProfile(state)
+	}
+	return state
+}
+
+func validServeMessages(messages []Message) error {
+	if len(messages) == 0 {
+		return errors.New(msg("messages 不能为空"))
+	}
+	for i, m := range messages {
+		switch m.Role {
+		case "system", "user", "assistant", "tool":
+		default:
+			return fmt.Errorf(msg("消息 %d 的 role 无效: %q"), i+1, m.Role)
+		}
+	}
+	return nil
+}
+
+func newServeResponse(state *ChatState, result *StreamResult) serveResponse {
+	return serveResponse{
+		Model:     state.Model,
+		Content:   result.Content,
+		Reasoning: result.Reasoning,
+		ToolCalls: result.ToolCalls,
+		RequestID: result.RequestID,
+		Usage:     result.Usage,
+		Done:      result.Done,
+	}
+}
+
+// 服务商返回的错误沿用其状态码，其他错误(网络、解析等)视为网关错误
+func serveErrorStatus(err error) int {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode >= 400 {
+		return apiErr.StatusCode
+	}
+	return http.StatusBadGateway
+}
+
+func writeServeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+	if status >= 500 {
+		fmt.Fprintf(os.Stderr, msg("请求失败: %v\n"), err)
+	}
+}
+
+// 回复内容的实时输出：服务模式下写入当前 HTTP 响应，否则写入流式输出目标
+func writeReplyChunk(state *ChatState, content string) {
+	if state.serveOutput != nil {
+		state.serveOutput(content)
+		return
+	}
+	writeStream(content)
+	flushStdout()
+}
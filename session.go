@@ -0,0 +1,281 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// 持久化的对话
+type Session struct {
+	SavedAt time.Time `json:"saved_at"`
+	Title   string    `json:"title,omitempty"`
+	Model   string    `json:"model"`
+	History []Message `json:"history"`
+
+	Branch   string            `json:"branch,omitempty"`
+	Branches map[string]Branch `json:"branches,omitempty"` // 包含当前分支
+
+	Overlays []string `json:"overlays,omitempty"`
+}
+
+func getAutosavePath() string {
+	return filepath.Join(ablsDir(), "autosave.json")
+}
+
+func getSessionsDir() string {
+	return filepath.Join(ablsDir(), "sessions")
+}
+
+// 交互模式正常退出时保存本次对话，供 -continue 使用
+func saveLastSession(state *ChatState) {
+	if state.isSingleCmd || len(state.History) <= 1 {
+		return
+	}
+
+	name := "session-" + time.Now().Format("20060102-150405")
+	if slug := titleSlug(state.Title); slug != "" {
+		name += "-" + slug
+	}
+	path := filepath.Join(getSessionsDir(), name+".json")
+	if err := saveSession(path, state); err != nil {
+		fmt.Fprintf(os.Stderr, msg("保存会话失败: %v\n"), err)
+		return
+	}
+	pruneSessions(*keepSessions)
+}
+
+// 只保留最近的 keep 个自动保存的会话(session-*.json)，其他文件不受影响
+func pruneSessions(keep int) {
+	if keep <= 0 {
+		return
+	}
+	paths, err := filepath.Glob(filepath.Join(getSessionsDir(), "session-*.json"))
+	if err != nil || len(paths) <= keep {
+		return
+	}
+
+	// 文件名以保存时间开头，按名称排序即按时间排序
+	sort.Strings(paths)
+	for _, path := range paths[:len(paths)-keep] {
+		if err := os.Remove(path); err != nil {
+			fmt.Fprintf(os.Stderr, msg("删除旧会话失败: %v\n"), err)
+		}
+	}
+}
+
+// 返回最近保存的会话文件及其修改时间
+func latestSessionFile() (string, time.Time) {
+	entries, err := os.ReadDir(getSessionsDir())
+	if err != nil {
+		return "", time.Time{}
+	}
+
+	var (
+		latest     string
+		latestTime time.Time
+	)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if info.ModTime().After(latestTime) {
+			latest = filepath.Join(getSessionsDir(), entry.Name())
+			latestTime = info.ModTime()
+		}
+	}
+	return latest, latestTime
+}
+
+func applySession(state *ChatState, session *Session) {
+	state.History = session.History
+	state.Pinned = map[int]bool{}
+	state.Branch = session.Branch
+	state.Branches = session.Branches
+	delete(state.Branches, currentBranch(state))
+	state.Title = session.Title
+	state.Overlays = session.Overlays
+	state.Role = ""
+	updatePrompt(state)
+	if session.Model != "" {
+		state.Model, state.ModelAlias = session.Model, ""
+		applyModelProfile(state)
+	}
+	if prompt := currentSystemPrompt(state); prompt != "" {
+		state.SystemPrompt = prompt
+	}
+}
+
+// -continue: 恢复最近一次保存或自动保存的对话
+func continueLastSession(state *ChatState) {
+	path, modTime := latestSessionFile()
+	if info, err := os.Stat(getAutosavePath()); err == nil && info.ModTime().After(modTime) {
+		path = getAutosavePath()
+	}
+	if path == "" {
+		fmt.Println(msg("没有可恢复的会话"))
+		return
+	}
+
+	session, err := loadSession(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, msg("恢复会话失败: %v\n"), err)
+		return
+	}
+	if path == getAutosavePath() {
+		os.Remove(path)
+	}
+
+	applySession(state, session)
+	fmt.Printf(msg("已恢复 %d 轮对话(%s)\n"), countUserTurns(state.History), path)
+}
+
+func countUserTurns(history []Message) int {
+	turns := 0
+	for _, msg := range history {
+		if msg.Role == "user" {
+			turns++
+		}
+	}
+	return turns
+}
+
+func saveSession(path string, state *ChatState) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(Session{
+		SavedAt: time.Now(),
+		Title:   state.Title,
+		Model:   state.Model,
+		History: state.History,
+
+		Branch:   state.Branch,
+		Branches: sessionBranches(state),
+
+		Overlays: state.Overlays,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+func loadSession(path string) (*Session, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf(msg("解析会话文件失败: %w"), err)
+	}
+	if len(session.History) == 0 {
+		return nil, fmt.Errorf(msg("会话文件为空: %s"), path)
+	}
+	return &session, nil
+}
+
+// 收到 SIGINT/SIGTERM 时自动保存对话并恢复终端状态，
+// 交互模式下请求进行中的 SIGINT 只取消该请求
+func installSignalHandler(state *ChatState) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		sig := <-sigCh
+		// 交互模式下 Ctrl+C 只中断正在输出的回复
+		for sig == os.Interrupt && !state.isSingleCmd && interruptStream(state) {
+			sig = <-sigCh
+		}
+
+		discardStreamDisplay()
+		finishLine()
+		fmt.Fprintf(os.Stderr, msg("收到信号 %v，正在保存对话...\n"), sig)
+
+		// 结束进行中的请求和输入等待，让主循环尽快释放 History
+		interruptStream(state)
+		if state.rl != nil {
+			state.rl.Close()
+		}
+		if !lockHistory(state, signalSaveTimeout) {
+			fmt.Fprintln(os.Stderr, msg("对话仍在处理中，未能自动保存"))
+		} else if len(state.History) > 1 {
+			if err := saveSession(getAutosavePath(), state); err != nil {
+				fmt.Fprintf(os.Stderr, msg("自动保存失败: %v\n"), err)
+			} else {
+				fmt.Fprintf(os.Stderr, msg("对话已保存到 %s\n"), getAutosavePath())
+			}
+		}
+		exitProgram(state, 1)
+	}()
+}
+
+// 收到退出信号后等待主循环释放 History 的最长时间
+const signalSaveTimeout = 3 * time.Second
+
+func lockHistory(state *ChatState, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for !state.historyMu.TryLock() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return true
+}
+
+// 启动时若存在比已保存会话更新的自动保存，询问是否恢复
+func offerAutosaveRestore(state *ChatState) {
+	path := getAutosavePath()
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	if _, latest := latestSessionFile(); !info.ModTime().After(latest) {
+		os.Remove(path)
+		return
+	}
+
+	session, err := loadSession(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, msg("读取自动保存失败: %v\n"), err)
+		}
+		return
+	}
+	defer os.Remove(path)
+
+	prompt := fmt.Sprintf(msg("检测到 %s 自动保存的对话(%d 条消息)，是否恢复? [y/N] "),
+		session.SavedAt.Format("2006-01-02 15:04:05"), len(session.History))
+	if !confirm(state, prompt) {
+		return
+	}
+
+	applySession(state, session)
+	fmt.Printf(msg("已恢复 %d 条消息\n"), len(state.History))
+}
+
+func confirm(state *ChatState, prompt string) bool {
+	state.rl.SetPrompt(prompt)
+	defer updatePrompt(state)
+
+	answer, err := state.rl.Readline()
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
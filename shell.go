@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// 执行 !cmd，输出会附加到下一条用户消息，只在 -allow-shell 时调用
+func runShellCommand(input string, state *ChatState) {
+	cmdLine := strings.TrimSpace(strings.TrimPrefix(input, "!"))
+	if cmdLine == "" {
+		fmt.Println(msg("用法: !<命令>"))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(*shellTimeout)*time.Second)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", cmdLine)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", cmdLine)
+	}
+
+	output, err := cmd.CombinedOutput()
+	text := strings.TrimRight(string(output), "\n")
+	if text != "" {
+		fmt.Println(text)
+	}
+
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		fmt.Printf(msg("错误：命令执行超时(%ds)\n"), *shellTimeout)
+		return
+	case err != nil:
+		fmt.Printf(msg("命令退出: %v\n"), err)
+	}
+
+	state.Pending = append(state.Pending, fmt.Sprintf("```\n$ %s\n%s\n```", cmdLine, text))
+	fmt.Println(msg("(输出将附加到下一条消息)"))
+}
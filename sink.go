@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"readline"
+)
+
+// 流式回复内容的全部输出目标：标准输出(或 -sink 指定的目标)以及 -tee 文件，
+// streamOut 通过 io.MultiWriter 同时写入它们
+var (
+	streamSinks = []io.Writer{os.Stdout}
+	streamOut   = io.MultiWriter(streamSinks...)
+)
+
+// 按 -sink 和 -tee 设置输出目标
+func setupStreamOutput() error {
+	if *sinkTarget != "" {
+		sink, err := openSink(*sinkTarget)
+		if err != nil {
+			return fmt.Errorf(msg("打开输出目标失败: %w"), err)
+		}
+		streamSinks = []io.Writer{sink}
+	}
+	if *teeFile != "" {
+		f, err := os.OpenFile(*teeFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return fmt.Errorf(msg("打开 -tee 文件失败: %w"), err)
+		}
+		streamSinks = append(streamSinks, &teeWriter{f: f})
+	}
+	// 只为终端输出着色，-tee 文件和 -sink 目标保持原文
+	if *highlightCode && streamToStdout() && colorEnabled() {
+		streamSinks[0] = &highlightWriter{w: os.Stdout}
+	}
+	if *typeDelay > 0 && streamToStdout() && readline.IsTerminal(int(os.Stdout.Fd())) {
+		streamSinks[0] = newTypewriterWriter(streamSinks[0], time.Duration(*typeDelay)*time.Millisecond)
+	}
+	streamOut = io.MultiWriter(streamSinks...)
+	return nil
+}
+
+// 流式内容是否直接显示在终端上
+func streamToStdout() bool {
+	if _, ok := streamSinks[0].(*typewriterWriter); ok {
+		return true
+	}
+	if _, ok := streamSinks[0].(*highlightWriter); ok {
+		return true
+	}
+	return streamSinks[0] == io.Writer(os.Stdout)
+}
+
+// -tee 文件：写入失败只提示一次，不影响其他输出目标
+type teeWriter struct {
+	f      *os.File
+	failed bool
+}
+
+func (t *teeWriter) Write(p []byte) (int, error) {
+	if t.failed {
+		return len(p), nil
+	}
+	if _, err := t.f.Write(p); err != nil {
+		t.failed = true
+		fmt.Fprintf(os.Stderr, msg("\n写入 -tee 文件失败，后续内容不再写入: %v\n"), err)
+	}
+	return len(p), nil
+}
+
+// 每条回复之间空一行
+func (t *teeWriter) EndTurn() error {
+	t.Write([]byte("\n\n"))
+	return nil
+}
+
+func (t *teeWriter) Close() error {
+	return t.f.Close()
+}
+
+// 支持回合结束通知的输出目标
+type turnSink interface {
+	EndTurn() error
+}
+
+// 按 -sink 打开输出目标：ws://、wss:// 或 fifo:<路径>
+func openSink(target string) (io.WriteCloser, error) {
+	if path, ok := strings.CutPrefix(target, "fifo:"); ok {
+		// 打开命名管道会阻塞到有读端连接
+		return os.OpenFile(path, os.O_WRONLY, 0)
+	}
+	if strings.HasPrefix(target, "ws://") || strings.HasPrefix(target, "wss://") {
+		return dialWebSocket(target)
+	}
+	return nil, fmt.Errorf(msg("不支持的输出目标 %q，可选 ws://...、wss://... 或 fifo:<路径>"), target)
+}
+
+func endSinkTurn() {
+	for _, w := range streamSinks {
+		if sink, ok := w.(turnSink); ok {
+			if err := sink.EndTurn(); err != nil {
+				fmt.Fprintf(os.Stderr, msg("写入输出目标失败: %v\n"), err)
+			}
+		}
+	}
+}
+
+func closeSink() {
+	for _, w := range streamSinks {
+		if closer, ok := w.(io.Closer); ok && w != io.Writer(os.Stdout) {
+			closer.Close()
+		}
+	}
+}
+
+// 最小化的 WebSocket 客户端，只发送文本帧
+type wsSink struct {
+	conn net.Conn
+}
+
+// 每个内容块作为一帧 JSON 发送
+type wsFrame struct {
+	Type    string `json:"type"` // content | done
+	Content string `json:"content,omitempty"`
+}
+
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+func dialWebSocket(rawURL string) (*wsSink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	host := u.Host
+	if u.Port() == "" {
+		if u.Scheme == "wss" {
+			host += ":443"
+		} else {
+			host += ":80"
+		}
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf(msg("生成 WebSocket 密钥失败: %w"), err)
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	var conn net.Conn
+	if u.Scheme == "wss" {
+		conn, err = tls.Dial("tcp", host, &tls.Config{ServerName: u.Hostname()})
+	} else {
+		conn, err = net.Dial("tcp", host)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	req, _ := http.NewRequest("GET", u.String(), nil)
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+
+	sum := sha1.Sum([]byte(key + wsGUID))
+	if resp.StatusCode != http.StatusSwitchingProtocols ||
+		resp.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(sum[:]) {
+		conn.Close()
+		return nil, errors.New(msg("WebSocket 握手失败: ") + resp.Status)
+	}
+	return &wsSink{conn: conn}, nil
+}
+
+func (ws *wsSink) Write(p []byte) (int, error) {
+	if err := ws.sendJSON(wsFrame{Type: "content", Content: string(p)}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (ws *wsSink) EndTurn() error {
+	return ws.sendJSON(wsFrame{Type: "done"})
+}
+
+func (ws *wsSink) Close() error {
+	ws.writeFrame(0x8, nil)
+	return ws.conn.Close()
+}
+
+func (ws *wsSink) sendJSON(frame wsFrame) error {
+	data, err := json.Marshal(frame)
+	if err != nil {
+		return err
+	}
+	return ws.writeFrame(0x1, data)
+}
+
+// 客户端发出的帧必须带掩码
+func (ws *wsSink) writeFrame(opcode byte, payload []byte) error {
+	header := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, 0x80|byte(n))
+	case n <= 0xFFFF:
+		header = append(header, 0x80|126)
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header = append(header, 0x80|127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+
+	mask := make([]byte, 4)
+	if _, err := rand.Read(mask); err != nil {
+		return fmt.Errorf(msg("生成 WebSocket 掩码失败: %w"), err)
+	}
+	header = append(header, mask...)
+
+	masked := make([]byte, len(payload))
+	for i, b := range payload {
+		masked[i] = b ^ mask[i%4]
+	}
+	_, err := ws.conn.Write(append(header, masked...))
+	return err
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 单轮请求的性能指标
+type TurnStat struct {
+	Latency      time.Duration
+	TTFT         time.Duration
+	TokensPerSec float64
+}
+
+func newTurnStat(startTime time.Time, result *StreamResult) TurnStat {
+	stat := TurnStat{Latency: time.Since(startTime), TTFT: result.TTFT}
+
+	tokens := estimateTokens(result.Content)
+	if result.Usage != nil && result.Usage.CompletionTokens > 0 {
+		tokens = result.Usage.CompletionTokens
+	}
+	if generation := stat.Latency - stat.TTFT; generation > 0 {
+		stat.TokensPerSec = float64(tokens) / generation.Seconds()
+	}
+	return stat
+}
+
+func showStats(state *ChatState) {
+	if len(state.Stats) == 0 {
+		fmt.Println(msg("暂无统计数据"))
+		return
+	}
+
+	fmt.Printf(msg("会话统计(%d 轮):\n"), len(state.Stats))
+	printStatsTable(state.Stats)
+}
+
+func printStatsTable(stats []TurnStat) {
+	latency := make([]float64, len(stats))
+	ttft := make([]float64, len(stats))
+	rate := make([]float64, len(stats))
+	for i, stat := range stats {
+		latency[i] = stat.Latency.Seconds()
+		ttft[i] = stat.TTFT.Seconds()
+		rate[i] = stat.TokensPerSec
+	}
+
+	fmt.Printf("  %-12s %10s %10s %10s\n", msg("指标"), msg("最小"), msg("平均"), msg("最大"))
+	printStatRow(msg("延迟(s)"), latency)
+	printStatRow(msg("首字(s)"), ttft)
+	printStatRow("tokens/s", rate)
+}
+
+func printStatRow(name string, values []float64) {
+	lo, hi, sum := values[0], values[0], 0.0
+	for _, v := range values {
+		lo = min(lo, v)
+		hi = max(hi, v)
+		sum += v
+	}
+	fmt.Printf("  %-12s %10.2f %10.2f %10.2f\n", name, lo, sum/float64(len(values)), hi)
+}
+
+const (
+	benchmarkPrompt  = "请用大约200字介绍一下长城的历史。"
+	maxBenchmarkRuns = 100
+)
+
+// 用固定问题连续请求 n 次测量当前模型的速度，不修改对话历史
+func runBenchmark(input string, state *ChatState) {
+	parts := strings.Fields(input)
+	if len(parts) != 2 {
+		fmt.Println(msg("用法: /benchmark <次数>"))
+		return
+	}
+	n, err := strconv.Atoi(parts[1])
+	if err != nil || n <= 0 || n > maxBenchmarkRuns {
+		fmt.Printf(msg("错误：次数必须为 1 到 %d 的整数\n"), maxBenchmarkRuns)
+		return
+	}
+
+	messages := []Message{
+		{Role: "system", Content: defaultSystemPrompt},
+		{Role: "user", Content: benchmarkPrompt},
+	}
+
+	var stats []TurnStat
+	runs := n
+	for i := 1; i <= n; i++ {
+		startTime := time.Now()
+		result, err := streamChatCompletion(state, state.Model, messages, false)
+		// Ctrl+C 结束整个基准测试，只统计已完成的请求
+		if errors.Is(err, errStreamCancelled) {
+			runs = i - 1
+			fmt.Printf(msg("\n已中断基准测试(完成 %d/%d)\n"), runs, n)
+			break
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, msg("  [%d/%d] 失败: %v\n"), i, n, err)
+			continue
+		}
+		recordUsage(state, state.Model, result.Usage)
+		stat := newTurnStat(startTime, result)
+		stats = append(stats, stat)
+		fmt.Printf(msg("  [%d/%d] 延迟 %.2fs, 首字 %.2fs, %.1f tokens/s\n"),
+			i, n, stat.Latency.Seconds(), stat.TTFT.Seconds(), stat.TokensPerSec)
+	}
+
+	if len(stats) == 0 {
+		if runs > 0 {
+			fmt.Println(msg("全部请求失败，没有统计数据"))
+		}
+		return
+	}
+	fmt.Printf(msg("基准测试 %s(成功 %d/%d):\n"), state.Model, len(stats), runs)
+	printStatsTable(stats)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// -stop-regex 编译后的正则，未设置时为 nil
+var stopPattern *regexp.Regexp
+
+func compileStopPattern() error {
+	if *stopRegex == "" {
+		return nil
+	}
+	pattern, err := regexp.Compile(*stopRegex)
+	if err != nil {
+		return fmt.Errorf(msg("无效的 -stop-regex: %w"), err)
+	}
+	if pattern.MatchString("") {
+		return errors.New(msg("-stop-regex 不能匹配空字符串"))
+	}
+	stopPattern = pattern
+	return nil
+}
+
+// 在已累计内容加上新数据块中查找停止正则，匹配可跨越数据块边界。
+// 命中时返回截断后本次还应追加的内容；匹配起点落在已累计内容中时直接截断 builder
+// (已实时输出的部分无法撤回)
+func truncateAtStop(builder *strings.Builder, content string) (string, bool) {
+	text := builder.String() + content
+	loc := stopPattern.FindStringIndex(text)
+	if loc == nil {
+		return content, false
+	}
+
+	if loc[0] < builder.Len() {
+		builder.Reset()
+		builder.WriteString(text[:loc[0]])
+		return "", true
+	}
+	return text[builder.Len():loc[0]], true
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sync/atomic"
+	"time"
+)
+
+// 流式响应中的一个事件。每个回答的增量内容各对应一个事件，
+// 流结束时再发送一个 Final 事件，携带请求ID、用量以及可能的错误
+type StreamEvent struct {
+	Index            int
+	Content          string
+	ReasoningContent string
+	FinishReason     string
+	LogProbs         []TokenLogProb
+	ToolCalls        []ToolCallDelta
+
+	Final     bool
+	Done      bool // 收到 [DONE]
+	RequestID string
+	Usage     *Usage
+	Err       error
+}
+
+// 已知支持 stream_options 的服务商，其他服务商(尤其是自建网关)可能因未知字段拒绝请求
+var streamUsageHosts = []string{
+	"dashscope.aliyuncs.com",
+	"dashscope-intl.aliyuncs.com",
+	"api.openai.com",
+	"api.deepseek.com",
+}
+
+func validStreamUsageMode() error {
+	switch *streamUsageMode {
+	case "auto", "on", "off":
+		return nil
+	}
+	return fmt.Errorf(msg("-stream-usage 只能为 auto|on|off，不支持 %q"), *streamUsageMode)
+}
+
+// anthropic 格式的流式响应本身就带用量，不需要该字段
+func streamUsageEnabled() bool {
+	if *requestFormat != "openai" {
+		return false
+	}
+	switch *streamUsageMode {
+	case "on":
+		return true
+	case "off":
+		return false
+	}
+	u, err := url.Parse(*apiEndpoint)
+	if err != nil {
+		return false
+	}
+	for _, host := range streamUsageHosts {
+		if u.Hostname() == host {
+			return true
+		}
+	}
+	return false
+}
+
+// 解析 SSE 数据流并按顺序发送事件，发送 Final 事件后关闭通道。
+// expected 为请求的回答数量，全部结束且收到用量后不再等待 [DONE]
+func readStreamEvents(body io.Reader, expected int) <-chan StreamEvent {
+	events := make(chan StreamEvent)
+
+	go func() {
+		defer close(events)
+
+		var (
+			reader    = bufio.NewReader(body)
+			requestID string
+			usage     *Usage
+			finished  int
+			anthropic *anthropicStream
+		)
+		if *requestFormat == "anthropic" {
+			anthropic = &anthropicStream{}
+		}
+		final := func(done bool, err error) {
+			events <- StreamEvent{Final: true, Done: done, RequestID: requestID, Usage: usage, Err: err}
+		}
+
+		for {
+			line, err := reader.ReadBytes('\n')
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					final(false, nil)
+				} else {
+					final(false, fmt.Errorf("%w: %v", errStreamBroken, err))
+				}
+				return
+			}
+
+			if len(line) < 6 || !bytes.HasPrefix(line, []byte("data: ")) {
+				continue
+			}
+
+			if bytes.Equal(line, []byte("data: [DONE]\n")) {
+				final(true, nil)
+				return
+			}
+
+			// Anthropic 格式以 message_stop 事件结束，没有 [DONE]
+			if anthropic != nil {
+				converted, done, err := anthropic.decode(line[6:])
+				requestID, usage = anthropic.requestID, anthropic.currentUsage()
+				if err != nil || done {
+					final(done, err)
+					return
+				}
+				for _, event := range converted {
+					events <- event
+				}
+				continue
+			}
+
+			var chunk StreamResponse
+			if err := json.Unmarshal(line[6:], &chunk); err != nil {
+				final(false, fmt.Errorf(msg("解析JSON失败: %w"), err))
+				return
+			}
+
+			if requestID == "" && chunk.ID != "" {
+				requestID = chunk.ID
+			}
+			if chunk.Usage != nil {
+				usage = chunk.Usage
+			}
+
+			for _, choice := range chunk.Choices {
+				event := StreamEvent{
+					Index:            choice.Index,
+					Content:          choice.Delta.Content,
+					ReasoningContent: choice.Delta.ReasoningContent,
+					FinishReason:     choice.FinishReason,
+					ToolCalls:        choice.Delta.ToolCalls,
+				}
+				if choice.LogProbs != nil {
+					event.LogProbs = choice.LogProbs.Content
+				}
+				events <- event
+				if choice.FinishReason != "" {
+					finished++
+				}
+			}
+
+			// 用量在结束块之后单独下发
+			if finished >= expected && usage != nil {
+				final(false, nil)
+				return
+			}
+		}
+	}()
+
+	return events
+}
+
+// 每次读到数据时重置计时器，超过 timeout 未收到数据则调用 cancel 中止请求
+type idleTimeoutReader struct {
+	r       io.Reader
+	timeout time.Duration
+	timer   *time.Timer
+	expired atomic.Bool
+}
+
+func newIdleTimeoutReader(r io.Reader, timeout time.Duration, cancel func()) *idleTimeoutReader {
+	ir := &idleTimeoutReader{r: r, timeout: timeout}
+	ir.timer = time.AfterFunc(timeout, func() {
+		ir.expired.Store(true)
+		cancel()
+	})
+	return ir
+}
+
+func (ir *idleTimeoutReader) Read(p []byte) (int, error) {
+	n, err := ir.r.Read(p)
+	if ir.expired.Load() {
+		return n, fmt.Errorf(msg("超过 %v 未收到数据"), ir.timeout)
+	}
+	if n > 0 {
+		ir.timer.Reset(ir.timeout)
+	}
+	return n, err
+}
+
+func (ir *idleTimeoutReader) Stop() {
+	ir.timer.Stop()
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func sseChunks(contents ...string) string {
+	var b strings.Builder
+	for _, c := range contents {
+		b.WriteString(`data: {"choices":[{"index":0,"delta":{"content":"` + c + `"}}]}` + "\n\n")
+	}
+	b.WriteString("data: [DONE]\n\n")
+	return b.String()
+}
+
+func TestProcessStreamResponseDedup(t *testing.T) {
+	tests := []struct {
+		name   string
+		dedup  bool
+		chunks []string
+		want   string
+	}{
+		{"重复数据块只保留一次", true, []string{"你好", "你好", "，世界"}, "你好，世界"},
+		{"多次重复", true, []string{"a", "a", "a", "b"}, "ab"},
+		{"不相邻的相同内容保留", true, []string{"a", "b", "a"}, "aba"},
+		{"未开启时原样保留", false, []string{"你好", "你好"}, "你好你好"},
+	}
+
+	defer func(old bool) { *dedupChunks = old }(*dedupChunks)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*dedupChunks = tt.dedup
+			result, err := processStreamResponse(strings.NewReader(sseChunks(tt.chunks...)), &ChatState{}, false, func() {})
+			if err != nil {
+				t.Fatalf("processStreamResponse: %v", err)
+			}
+			if result.Content != tt.want {
+				t.Errorf("Content = %q, want %q", result.Content, tt.want)
+			}
+			if !result.Done {
+				t.Error("Done = false, want true")
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const summarizeInstruction = "请总结以下文件的主要内容，列出关键要点。"
+
+// 单独发送一次总结请求，确认后才加入对话历史
+func handleSummarize(input string, state *ChatState) {
+	path := strings.TrimSpace(strings.TrimPrefix(input, "/summarize"))
+	if path == "" {
+		fmt.Println(msg("用法: /summarize <文件路径>"))
+		return
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, msg("读取 %s 失败: %v\n"), path, err)
+		return
+	}
+	if info.IsDir() {
+		fmt.Printf(msg("错误：%s 是目录\n"), path)
+		return
+	}
+	if info.Size() > int64(*maxContextBytes) {
+		fmt.Printf(msg("错误：文件超出大小限制 %d 字节(-max-context-bytes)\n"), *maxContextBytes)
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, msg("读取 %s 失败: %v\n"), path, err)
+		return
+	}
+
+	request := Message{
+		Role:    "user",
+		Content: summarizeInstruction + "\n\n" + contextMessage(ContextDoc{Path: path, Content: string(data)}),
+	}
+	messages := []Message{{Role: "system", Content: currentSystemPrompt(state)}, request}
+
+	if !*quiet {
+		printLive(fmt.Sprintf("AI(%s): ", state.Model))
+	}
+	startTime := time.Now()
+	result, err := streamChatCompletion(state, state.Model, messages, true)
+	finishLine()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, msg("错误: %v\n"), err)
+		return
+	}
+	recordUsage(state, state.Model, result.Usage)
+	state.Stats = append(state.Stats, newTurnStat(startTime, result))
+
+	if state.rl != nil && confirm(state, msg("是否将本次总结加入对话历史? [y/N] ")) {
+		state.History = append(state.History, request, Message{Role: "assistant", Content: result.Content})
+		fmt.Println(msg("已加入对话历史"))
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import "strings"
+
+const (
+	thinkOpenTag  = "<think>"
+	thinkCloseTag = "</think>"
+)
+
+// 从内容流中分离 <think>...</think> 包裹的思考过程。
+// 标签可能被拆到多个数据块中，末尾疑似不完整的标签暂存到下一块再判断
+type thinkFilter struct {
+	inThink bool
+	pending string
+}
+
+func (f *thinkFilter) Feed(text string) (content, thinking string) {
+	var out, think strings.Builder
+	emit := func(s string) {
+		if f.inThink {
+			think.WriteString(s)
+		} else {
+			out.WriteString(s)
+		}
+	}
+
+	buf := f.pending + text
+	f.pending = ""
+	for {
+		tag := thinkOpenTag
+		if f.inThink {
+			tag = thinkCloseTag
+		}
+		if idx := strings.Index(buf, tag); idx >= 0 {
+			emit(buf[:idx])
+			buf = buf[idx+len(tag):]
+			f.inThink = !f.inThink
+			continue
+		}
+
+		keep := partialTagSuffix(buf, tag)
+		emit(buf[:len(buf)-keep])
+		f.pending = buf[len(buf)-keep:]
+		break
+	}
+	return out.String(), think.String()
+}
+
+// 流结束时输出暂存的内容
+func (f *thinkFilter) Flush() (content, thinking string) {
+	rest := f.pending
+	f.pending = ""
+	if f.inThink {
+		return "", rest
+	}
+	return rest, ""
+}
+
+// s 末尾与 tag 开头重合的最长长度
+func partialTagSuffix(s, tag string) int {
+	for k := min(len(tag)-1, len(s)); k > 0; k-- {
+		if strings.HasSuffix(s, tag[:k]) {
+			return k
+		}
+	}
+	return 0
+}
+
+// 处理完整文本，用于非流式响应
+func stripThinkTags(text string) (content, thinking string) {
+	var f thinkFilter
+	content, thinking = f.Feed(text)
+	restContent, restThinking := f.Flush()
+	return content + restContent, thinking + restThinking
+}
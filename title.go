@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+const titlePrompt = "请用3到6个词为以上对话生成一个简短的标题，只输出标题本身，不要标点和引号。"
+
+func handleTitleCommand(input string, state *ChatState) {
+	if text := strings.TrimSpace(strings.TrimPrefix(input, "/title")); text != "" {
+		state.Title = text
+		updatePrompt(state)
+		fmt.Printf(msg("会话标题: %s\n"), state.Title)
+		return
+	}
+
+	if len(state.History) <= 1 {
+		fmt.Println(msg("错误：对话为空，无法生成标题"))
+		return
+	}
+
+	messages := append(buildMessages(state), Message{Role: "user", Content: titlePrompt})
+	result, err := streamChatCompletion(state, state.Model, messages, false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, msg("生成标题失败: %v\n"), err)
+		return
+	}
+
+	state.Title = strings.Trim(strings.TrimSpace(result.Content), `"'“”《》`)
+	updatePrompt(state)
+	fmt.Printf(msg("会话标题: %s\n"), state.Title)
+}
+
+func promptString(state *ChatState) string {
+	prompt := ""
+	if state.Role != "" {
+		prompt += "(" + state.Role + ")"
+	}
+	if state.Title != "" {
+		prompt += "[" + state.Title + "]"
+	}
+	return prompt + "> "
+}
+
+func updatePrompt(state *ChatState) {
+	if state.rl != nil {
+		state.rl.SetPrompt(promptString(state))
+	}
+}
+
+// 将标题转换为可用作文件名的形式
+func titleSlug(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.TrimSpace(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteRune('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// -tools 加载的工具定义，按原样放入请求的 tools 字段。
+// 只负责协议的传递：模型发起的工具调用会显示出来，结果由用户通过 /tool-result 提供
+var toolDefs []json.RawMessage
+
+// 助手消息中的一次工具调用
+type ToolCall struct {
+	ID       string       `json:"id"`
+	Type     string       `json:"type"`
+	Function ToolFunction `json:"function"`
+}
+
+type ToolFunction struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// 流式响应中工具调用的增量，同一调用的 arguments 分多块到达，按 index 拼接
+type ToolCallDelta struct {
+	Index    int          `json:"index"`
+	ID       string       `json:"id,omitempty"`
+	Type     string       `json:"type,omitempty"`
+	Function ToolFunction `json:"function"`
+}
+
+func loadTools() error {
+	if *toolsFile == "" {
+		return nil
+	}
+	if *requestFormat != "openai" {
+		return errors.New(msg("-tools 只支持 openai 请求格式"))
+	}
+	data, err := os.ReadFile(*toolsFile)
+	if err != nil {
+		return err
+	}
+
+	var tools []json.RawMessage
+	if err := json.Unmarshal(data, &tools); err != nil {
+		return fmt.Errorf(msg("解析工具定义失败，应为 JSON 数组: %w"), err)
+	}
+	for i, raw := range tools {
+		var tool struct {
+			Type     string `json:"type"`
+			Function struct {
+				Name string `json:"name"`
+			} `json:"function"`
+		}
+		if err := json.Unmarshal(raw, &tool); err != nil || tool.Type != "function" || tool.Function.Name == "" {
+			return fmt.Errorf(msg("工具 %d 格式无效，应为 {\"type\": \"function\", \"function\": {\"name\": ...}}"), i+1)
+		}
+	}
+	toolDefs = tools
+	return nil
+}
+
+// 把增量拼接到对应的工具调用上
+func mergeToolCallDeltas(calls []ToolCall, deltas []ToolCallDelta) []ToolCall {
+	for _, delta := range deltas {
+		for len(calls) <= delta.Index {
+			calls = append(calls, ToolCall{Type: "function"})
+		}
+		call := &calls[delta.Index]
+		if delta.ID != "" {
+			call.ID = delta.ID
+		}
+		if delta.Type != "" {
+			call.Type = delta.Type
+		}
+		call.Function.Name += delta.Function.Name
+		call.Function.Arguments += delta.Function.Arguments
+	}
+	return calls
+}
+
+func printToolCalls(state *ChatState, calls []ToolCall) {
+	finishLine()
+	for _, call := range calls {
+		fmt.Println(colorize(ansiYellow, fmt.Sprintf(msg("[工具调用] %s(%s) id=%s"), call.Function.Name, call.Function.Arguments, call.ID)))
+	}
+	if !*quiet && !state.isSingleCmd {
+		fmt.Println(msg("(用 /tool-result <id> <JSON> 提供结果，全部提供后自动继续)"))
+	}
+}
+
+// 最后一条助手消息中尚未提供结果的工具调用
+func pendingToolCalls(state *ChatState) []ToolCall {
+	answered := map[string]bool{}
+	for i := len(state.History) - 1; i >= 0; i-- {
+		m := state.History[i]
+		switch m.Role {
+		case "tool":
+			answered[m.ToolCallID] = true
+			continue
+		case "assistant":
+			var pending []ToolCall
+			for _, call := range m.ToolCalls {
+				if !answered[call.ID] {
+					pending = append(pending, call)
+				}
+			}
+			return pending
+		}
+		return nil
+	}
+	return nil
+}
+
+// /tool-result <id> <JSON>: 以 tool 角色追加工具结果，所有调用都有结果后请求模型继续回答
+func handleToolResult(input string, state *ChatState) {
+	parts := strings.SplitN(input, " ", 3)
+	if len(parts) < 3 || strings.TrimSpace(parts[2]) == "" {
+		fmt.Println(msg("用法: /tool-result <id> <JSON>"))
+		return
+	}
+	id, result := parts[1], strings.TrimSpace(parts[2])
+
+	pending := pendingToolCalls(state)
+	found := false
+	for _, call := range pending {
+		found = found || call.ID == id
+	}
+	if !found {
+		fmt.Printf(msg("错误：没有等待结果的工具调用 %q\n"), id)
+		return
+	}
+	if !json.Valid([]byte(result)) {
+		fmt.Println(msg("错误：工具结果不是合法的 JSON"))
+		return
+	}
+
+	state.History = append(state.History, Message{Role: "tool", Content: result, ToolCallID: id})
+	if len(pending) > 1 {
+		fmt.Printf(msg("已添加工具结果，还有 %d 个调用等待结果\n"), len(pending)-1)
+		return
+	}
+	if _, err := processAIResponse(state, streamMode(state)); err != nil {
+		finishLine()
+		fmt.Fprintf(os.Stderr, msg("错误: %v\n"), err)
+	}
+	finishLine()
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// -type-delay: 逐字输出流式内容，用于录屏演示。内容先进入队列由后台逐字输出，
+// 读取响应不受影响，回复内容和耗时统计照常记录
+type typewriterWriter struct {
+	w       io.Writer
+	delay   time.Duration
+	queue   chan string // 空字符串表示一轮回复结束
+	pending sync.WaitGroup
+	discard atomic.Bool // 置位时跳过队列中尚未输出的内容
+}
+
+func newTypewriterWriter(w io.Writer, delay time.Duration) *typewriterWriter {
+	t := &typewriterWriter{w: w, delay: delay, queue: make(chan string, 1024)}
+	go t.run()
+	return t
+}
+
+func (t *typewriterWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	t.pending.Add(1)
+	t.queue <- string(p)
+	return len(p), nil
+}
+
+// 被包装的目标(如 -highlight)在排队内容输出完后才收到回合结束通知
+func (t *typewriterWriter) EndTurn() error {
+	t.pending.Add(1)
+	t.queue <- ""
+	return nil
+}
+
+func (t *typewriterWriter) run() {
+	for s := range t.queue {
+		if s == "" {
+			if sink, ok := t.w.(turnSink); ok {
+				sink.EndTurn()
+			}
+		}
+		for _, r := range s {
+			if t.discard.Load() {
+				break
+			}
+			io.WriteString(t.w, string(r))
+			time.Sleep(t.delay)
+		}
+		t.pending.Done()
+	}
+}
+
+// 等待排队的内容全部输出，之后的输出才不会与回复交错。
+// finishLine 会先调用这里，成功、出错、中断和续传等路径换行前都会等待
+func waitStreamDisplay() {
+	if t, ok := streamSinks[0].(*typewriterWriter); ok {
+		t.pending.Wait()
+	}
+}
+
+// Ctrl+C 中断回复时丢弃还没逐字输出的内容，不再等它慢慢打完
+func discardStreamDisplay() {
+	if t, ok := streamSinks[0].(*typewriterWriter); ok {
+		t.discard.Store(true)
+		t.pending.Wait()
+		t.discard.Store(false)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	varNamePattern     = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	varPlaceholderExpr = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+)
+
+// 替换消息中的 {{名称}}，未定义的占位符保持原样
+func substituteVars(state *ChatState, text string) string {
+	var missing []string
+	result := varPlaceholderExpr.ReplaceAllStringFunc(text, func(match string) string {
+		name := varPlaceholderExpr.FindStringSubmatch(match)[1]
+		if value, ok := state.Vars[name]; ok {
+			return value
+		}
+		missing = append(missing, name)
+		return match
+	})
+
+	if len(missing) > 0 && *warnUnsetVars {
+		fmt.Fprintf(os.Stderr, msg("警告：未定义的变量: %s\n"), strings.Join(missing, ", "))
+	}
+	return result
+}
+
+func handleSetVar(input string, state *ChatState) {
+	args := strings.TrimSpace(strings.TrimPrefix(input, "/set"))
+	name, value, _ := strings.Cut(args, " ")
+	value = strings.TrimSpace(value)
+	if name == "" || value == "" {
+		fmt.Println(msg("用法: /set <名称> <值>"))
+		return
+	}
+	if !varNamePattern.MatchString(name) {
+		fmt.Println(msg("错误：变量名只能包含字母、数字和下划线，且不能以数字开头"))
+		return
+	}
+
+	if state.Vars == nil {
+		state.Vars = map[string]string{}
+	}
+	state.Vars[name] = value
+	fmt.Printf(msg("已设置变量 %s\n"), name)
+}
+
+func handleUnsetVar(input string, state *ChatState) {
+	parts := strings.Fields(input)
+	if len(parts) != 2 {
+		fmt.Println(msg("用法: /unset <名称>"))
+		return
+	}
+	if _, ok := state.Vars[parts[1]]; !ok {
+		fmt.Printf(msg("错误：变量 %s 未定义\n"), parts[1])
+		return
+	}
+	delete(state.Vars, parts[1])
+	fmt.Printf(msg("已删除变量 %s\n"), parts[1])
+}
+
+func listVars(state *ChatState) {
+	if len(state.Vars) == 0 {
+		fmt.Println(msg("暂无变量"))
+		return
+	}
+
+	names := make([]string, 0, len(state.Vars))
+	for name := range state.Vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Println(msg("变量:"))
+	for _, name := range names {
+		fmt.Printf("  %s = %s\n", name, state.Vars[name])
+	}
+}